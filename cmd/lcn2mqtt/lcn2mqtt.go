@@ -3,12 +3,18 @@ package main
 import (
 	"fmt"
 
+	"github.com/MyChaOS87/reverseLCN/internal/bridge"
 	"github.com/MyChaOS87/reverseLCN/internal/cmd"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/poller"
 	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
-	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
 	"github.com/MyChaOS87/reverseLCN/pkg/broker/mqtt"
-	"github.com/MyChaOS87/reverseLCN/pkg/broker/null"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/message"
 	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/metrics"
+	"github.com/MyChaOS87/reverseLCN/pkg/pcapng"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
 )
@@ -17,58 +23,117 @@ func main() {
 	ctx, cancel, cfg := cmd.Init()
 	defer cancel()
 
-	var broker broker.Broker
-	if cfg.Mqtt.Enabled {
-		broker = mqtt.NewBroker(
-			mqtt.Broker(cfg.Mqtt.Broker))
-	} else {
-		broker = null.NewBroker()
+	catalog, err := monitor.NewCatalog(cfg.Devices)
+	if err != nil {
+		log.Fatalf("Invalid devices configuration: %s", err)
 	}
 
+	broker := cmd.NewBroker(&cfg.Mqtt,
+		mqtt.Will(bridge.StateTopic(cfg.Mqtt.RootTopic), "offline", 1, true))
+
 	broker.Run(ctx, cancel)
 
-	port := serial.NewPort(
-		serial.BaudRate(cfg.Serial.BaudRate),
-		serial.PortName(cfg.Serial.Port),
-		serial.Deserializer(lcn.Deserialize),
+	var observers []serial.Observer
+
+	if cfg.Capture.Record != "" {
+		recorder, err := capture.Create(cfg.Capture.Record)
+		if err != nil {
+			log.Fatalf("Cannot record: %s", err)
+		}
+		defer recorder.Close()
+
+		observers = append(observers, recorder)
+	}
+
+	if cfg.Capture.Pcapng != "" {
+		exporter, err := pcapng.Create(cfg.Capture.Pcapng)
+		if err != nil {
+			log.Fatalf("Cannot export: %s", err)
+		}
+		defer exporter.Close()
+
+		observers = append(observers, pcapng.NewObserver(exporter))
+	}
+
+	// publishes received frames and, unless legacy, sent frames
+	observers = append(observers, message.NewPublisher(broker, cfg.Mqtt.RootTopic, cfg.Mqtt.LegacyMessages))
+
+	port := cmd.NewPort(cfg, observers...)
+
+	registry := metrics.NewRegistry()
+	frames := cmd.RegisterPortMetrics(registry, port)
+	cmd.RegisterBrokerMetrics(registry, broker)
+
+	if cfg.Metrics.Listen != "" {
+		go registry.Listen(ctx, cfg.Metrics.Listen)
+	}
+
+	// output states derived from the frames handled by the bridge
+	outputs := state.NewRegistry(state.Catalog(catalog))
+
+	lcnBridge := bridge.New(broker, port,
+		bridge.RootTopic(cfg.Mqtt.RootTopic),
+		bridge.Source(byte(cfg.Lcn.Source)),
+		bridge.DiscoveryPrefix(cfg.Mqtt.DiscoveryPrefix),
+		bridge.Catalog(catalog),
+		bridge.State(outputs),
 	)
+	lcnBridge.Run(ctx)
+
 	port.Run(ctx, cancel, func(pkt packet.Packet) {
 		log.Infof("%s", pkt.ToNiceString())
 
 		if lcn, ok := pkt.(*lcn.LcnPacket); ok {
-			broker.
-				Topic(
-					fmt.Sprintf("%s/segment/%d/target/%d/",
-						cfg.Mqtt.RootTopic,
-						lcn.Seg,
-						lcn.Dst)).
-				Publish(lcn)
+			frames.Count(lcn)
+
+			lcnBridge.Handle(lcn)
 		} else {
 			log.Debug("Not a LCN Packet")
 		}
 	})
 
+	// a replay answers no queries
+	if cfg.Poll.Enabled && cfg.Capture.Replay == "" {
+		pollOptions := []poller.Option{
+			poller.Source(byte(cfg.Lcn.Source)),
+			poller.Interval(cfg.Poll.Interval),
+		}
+
+		if cfg.Poll.Spacing > 0 {
+			pollOptions = append(pollOptions, poller.Spacing(cfg.Poll.Spacing))
+		}
+
+		poller.New(port, catalog, pollOptions...).Run(ctx)
+	}
+
 	broker.Topic(
 		fmt.Sprintf(
 			"%s/in",
 			cfg.Mqtt.RootTopic)).
-		Subscribe(lcn.LcnPacket{}, func(_ string, data interface{}) {
-			if pkt, ok := data.(*lcn.LcnPacket); ok {
-				log.Infof("MQTT callback got LCN: %s", pkt.ToNiceString())
-				buf, err := pkt.Serialize()
-				if err != nil {
-					log.Error("Could not Serialize LCN: %s", pkt.ToNiceString())
-
-					return
-				}
-
-				go port.Send(buf)
-			} else {
-				log.Errorf("Could not interpret MQTT: %s", data)
+		SubscribeString(func(_ string, data string) {
+			pkt, err := message.ParseInput([]byte(data), cfg.Mqtt.LegacyMessages, byte(cfg.Lcn.Source), catalog.Segment)
+			if err != nil {
+				log.Errorf("Could not interpret MQTT %s: %s", data, err)
+
+				return
+			}
+
+			if err := lcnBridge.Interlock(pkt); err != nil {
+				log.Errorf("Refusing MQTT %s: %s", data, err)
+
+				return
 			}
+
+			log.Infof("MQTT callback got LCN: %s", pkt.ToNiceString())
+
+			// sent like the commands on the output topics, the state follows once acknowledged
+			lcnBridge.Send(ctx, pkt)
 		})
 
 	<-ctx.Done()
 
 	log.Errorf("context done: %s", ctx.Err().Error())
+
+	lcnBridge.Close()
+	broker.Close()
 }
@@ -0,0 +1,85 @@
+//nolint:gochecknoglobals
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/MyChaOS87/reverseLCN/internal/checksum"
+	"github.com/MyChaOS87/reverseLCN/internal/cmd"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var (
+	variants = flag.String("variants", strings.Join(lcn.ChecksumNames(), ","), "checksum variants to compare")
+	framing  = flag.String("framing", lcn.DefaultChecksum, "checksum of the framing, frames failing it are still compared")
+)
+
+// lcnChecksum replays captures given as arguments, or capture.replay, and reports how many frames
+// fail each checksum variant.
+func main() {
+	flag.Parse()
+
+	ctx, cancel, cfg := cmd.Init()
+	defer cancel()
+
+	files := flag.Args()
+	if len(files) == 0 && cfg.Capture.Replay != "" {
+		files = []string{cfg.Capture.Replay}
+	}
+
+	if len(files) == 0 {
+		log.Fatalf("No captures given")
+	}
+
+	verifier, err := checksum.NewVerifier(strings.Split(*variants, ",")...)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	// frames are told apart by the gaps between them, so frames with a wrong checksum are kept
+	framingChecksum, err := lcn.ChecksumByName(*framing)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	codec := lcn.NewCodec(lcn.Checksum(framingChecksum), lcn.AcceptInvalid(true))
+
+	for _, file := range files {
+		options := []capture.Option{
+			capture.Deserializer(codec.Deserialize),
+			capture.Speed(0),
+		}
+
+		if cfg.Serial.MaxGap > 0 {
+			options = append(options, capture.MaxGap(cfg.Serial.MaxGap))
+		}
+
+		if cfg.Serial.MaxFrameAge > 0 {
+			options = append(options, capture.MaxFrameAge(cfg.Serial.MaxFrameAge))
+		}
+
+		replay := capture.NewReplay(file, options...)
+		replay.Run(ctx, cancel, func(pkt packet.Packet) {
+			if lcnPacket, ok := pkt.(*lcn.LcnPacket); ok {
+				if err := verifier.AddPacket(lcnPacket); err != nil {
+					log.Warnf("Cannot check %s: %s", lcnPacket.ToNiceString(), err)
+				}
+			}
+		})
+
+		select {
+		case <-replay.Done():
+		case <-ctx.Done():
+			log.Fatalf("context done: %s", ctx.Err().Error())
+		}
+	}
+
+	if err := verifier.Report(os.Stdout); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,50 @@
+//nolint:gochecknoglobals
+package main
+
+import (
+	"flag"
+
+	"github.com/MyChaOS87/reverseLCN/internal/cmd"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/pcapng"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var output = flag.String("w", "lcn.pcapng", "pcapng file or named pipe to write to")
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel, cfg := cmd.Init()
+	defer cancel()
+
+	log.Infof("Writing to %s", *output)
+
+	writer, err := pcapng.Create(*output)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	defer writer.Close()
+
+	port := cmd.NewPort(cfg, pcapng.NewObserver(writer))
+
+	port.Run(ctx, cancel, func(pkt packet.Packet) {
+		log.Infof("%s", pkt.ToNiceString())
+	})
+
+	// a replayed capture ends, the serial device does not
+	var replayDone <-chan struct{}
+	if replay, ok := port.(*capture.Replay); ok {
+		replayDone = replay.Done()
+	}
+
+	select {
+	case <-replayDone:
+		log.Infof("Capture %s exported to %s", cfg.Capture.Replay, *output)
+	case <-ctx.Done():
+		log.Errorf("context done: %s", ctx.Err().Error())
+	case <-writer.Failed():
+		log.Errorf("Stopped writing to %s: %s", *output, writer.Err())
+	}
+}
@@ -2,43 +2,128 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/MyChaOS87/reverseLCN/internal/cmd"
 	"github.com/MyChaOS87/reverseLCN/internal/monitor"
-	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
-	"github.com/MyChaOS87/reverseLCN/pkg/broker"
-	"github.com/MyChaOS87/reverseLCN/pkg/broker/mqtt"
-	"github.com/MyChaOS87/reverseLCN/pkg/broker/null"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/internal/tui"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/message"
 	"github.com/MyChaOS87/reverseLCN/pkg/log"
 )
 
+var (
+	interactive = flag.Bool("tui", false, "show the frames in a full screen terminal UI instead of logging them")
+	infoReport  = flag.Duration("info", 0, "tally the INFO bytes per command and source, reported at this interval and on exit")
+)
+
 func main() {
+	flag.Parse()
+
 	ctx, cancel, cfg := cmd.Init()
 	defer cancel()
 
-	var broker broker.Broker
-	if cfg.Mqtt.Enabled {
-		broker = mqtt.NewBroker(
-			mqtt.Broker(cfg.Mqtt.Broker))
-	} else {
-		broker = null.NewBroker()
+	switch {
+	case *interactive:
+		cmd.KeepLogOffScreen(cfg, "discard")
+	case *infoReport > 0:
+		cmd.KeepLogOffScreen(cfg, "stderr")
 	}
 
-	dataStore := monitor.NewDataStore()
+	catalog, err := monitor.NewCatalog(cfg.Devices)
+	if err != nil {
+		log.Fatalf("Invalid devices configuration: %s", err)
+	}
+
+	broker := cmd.NewBroker(&cfg.Mqtt)
+
+	dataStore := monitor.NewDataStore(catalog)
+	infoTally := monitor.NewInfoTally(catalog)
+	outputs := state.NewRegistry(state.Catalog(catalog))
+
+	outputs.Subscribe(func(events []state.Event) {
+		for _, event := range events {
+			logStateChange(event)
+		}
+	})
 
 	broker.Run(ctx, cancel)
 
+	// frames are published in the current or the legacy format
 	broker.Topic(fmt.Sprintf(
-		"%s/#",
+		"%s/segment/#",
 		cfg.Mqtt.RootTopic)).
-		Subscribe(lcn.LcnPacket{}, func(_ string, in interface{}) {
-			if pkt, ok := in.(*lcn.LcnPacket); ok {
-				dataStore.Add(*pkt)
+		SubscribeString(func(topic string, data string) {
+			pkt, err := message.Parse([]byte(data))
+			if err != nil {
+				log.Warnf("Cannot parse frame on %s: %s", topic, err)
+
+				return
 			}
+
+			dataStore.Add(*pkt)
+			infoTally.Add(pkt)
+			outputs.Handle(pkt)
 		})
 
+	if *infoReport > 0 {
+		defer reportInfo(infoTally)
+
+		if !*interactive {
+			go func() {
+				ticker := time.NewTicker(*infoReport)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						reportInfo(infoTally)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+	}
+
+	if *interactive {
+		if err := tui.Run(ctx, dataStore, outputs); err != nil {
+			// the log is discarded while the terminal UI is shown
+			fmt.Fprintf(os.Stderr, "terminal UI failed: %s\n", err)
+		}
+
+		return
+	}
+
 	<-ctx.Done()
 
 	log.Errorf("context done: %s", ctx.Err().Error())
 }
+
+func logStateChange(event state.Event) {
+	output := event.Output
+
+	previous := "?"
+	if event.Previous.Known {
+		previous = onOff(event.Previous.On)
+	}
+
+	log.Infof("STATE: %s %s %s -> %s", output.Key, output.Name, previous, onOff(output.On))
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func reportInfo(infoTally *monitor.InfoTally) {
+	if err := infoTally.Report(os.Stdout); err != nil {
+		log.Errorf("%s", err)
+	}
+}
@@ -0,0 +1,119 @@
+//nolint:gochecknoglobals
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/MyChaOS87/reverseLCN/internal/cmd"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/scan"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var (
+	output   = flag.String("o", "-", "file to write the inventory to, - for stdout")
+	segments = flag.String("segments", "", "comma separated segments to probe, the configured ones by default")
+	spacing  = flag.Duration("spacing", 0, "pause between two probes, 50ms by default")
+	wait     = flag.Duration("wait", 0, "time to wait for replies after probing a segment, 2s by default")
+	observe  = flag.Duration("observe", 0, "time to observe the traffic after probing, 30s by default")
+)
+
+// inventory is pasted into the configuration in place of the devices section.
+type inventory struct {
+	Devices scan.Inventory `yaml:"devices"`
+}
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel, cfg := cmd.Init()
+	defer cancel()
+
+	if *output == "-" {
+		cmd.KeepLogOffScreen(cfg, "stderr")
+	}
+
+	catalog, err := monitor.NewCatalog(cfg.Devices)
+	if err != nil {
+		log.Fatalf("Invalid devices configuration: %s", err)
+	}
+
+	options := []scan.Option{
+		scan.Source(byte(cfg.Lcn.Source)),
+		scan.Catalog(catalog),
+	}
+
+	if *segments != "" {
+		var ids []int
+
+		for _, field := range strings.Split(*segments, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				log.Fatalf("Invalid segment %q: %s", field, err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		options = append(options, scan.Segments(ids...))
+	}
+
+	if *spacing > 0 {
+		options = append(options, scan.Spacing(*spacing))
+	}
+
+	if *wait > 0 {
+		options = append(options, scan.Wait(*wait))
+	}
+
+	if *observe > 0 {
+		options = append(options, scan.Observe(*observe))
+	}
+
+	port := cmd.NewPort(cfg)
+	scanner := scan.New(port, options...)
+
+	port.Run(ctx, cancel, func(pkt packet.Packet) {
+		log.Infof("%s", pkt.ToNiceString())
+
+		if lcn, ok := pkt.(*lcn.LcnPacket); ok {
+			scanner.Handle(lcn)
+		}
+	})
+
+	// an interrupted scan still writes what was found so far
+	if err := scanner.Scan(ctx); err != nil {
+		log.Warnf("Scan incomplete: %s", err)
+	}
+
+	var writer io.Writer = os.Stdout
+
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Cannot write inventory: %s", err)
+		}
+		defer file.Close()
+
+		writer = file
+	}
+
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2) //nolint:gomnd
+
+	if err := encoder.Encode(inventory{Devices: scanner.Inventory()}); err != nil {
+		log.Errorf("Cannot write inventory: %s", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		log.Errorf("Cannot write inventory: %s", err)
+	}
+}
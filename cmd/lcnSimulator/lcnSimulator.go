@@ -0,0 +1,75 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/hex"
+	"os"
+
+	"github.com/MyChaOS87/reverseLCN/internal/cmd"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/simulator"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+)
+
+func main() {
+	ctx, cancel, cfg := cmd.Init()
+	defer cancel()
+
+	options := []simulator.Option{
+		simulator.Devices(cfg.Devices),
+	}
+
+	for _, sensor := range cfg.Simulator.Sensors {
+		if sensor.Interval <= 0 {
+			log.Fatalf("Sensor on module %d needs an interval", sensor.Module)
+		}
+
+		payload, err := hex.DecodeString(sensor.Payload)
+		if err != nil {
+			log.Fatalf("Invalid payload of sensor on module %d: %s", sensor.Module, err)
+		}
+
+		options = append(options, simulator.Sensors(simulator.Sensor{
+			Packet: &lcn.LcnPacket{
+				Src:     byte(sensor.Module),
+				Seg:     byte(sensor.Segment),
+				Dst:     byte(sensor.Target),
+				Cmd:     byte(sensor.Cmd),
+				Payload: payload,
+			},
+			Interval: sensor.Interval,
+		}))
+	}
+
+	pty, err := simulator.OpenPTY()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	defer pty.Close()
+
+	log.Infof("Simulating LCN bus on %s", pty.SlaveName)
+
+	if link := cfg.Simulator.Link; link != "" {
+		os.Remove(link)
+
+		if err := os.Symlink(pty.SlaveName, link); err != nil {
+			log.Fatalf("Cannot link %s: %s", link, err)
+		}
+		defer os.Remove(link)
+
+		log.Infof("Simulating LCN bus on %s", link)
+	}
+
+	go func() {
+		if err := simulator.New(options...).Run(ctx, pty); err != nil {
+			log.Errorf("Simulator stopped: %s", err)
+		}
+
+		cancel()
+	}()
+
+	<-ctx.Done()
+
+	log.Errorf("context done: %s", ctx.Err().Error())
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -15,21 +16,113 @@ const (
 )
 
 type SerialConfig struct {
-	Port     string
-	BaudRate int
+	Port         string // device or URL: serial:///dev/ttyUSB0, tcp://host:port, rfc2217://host:port
+	BaudRate     int
+	BusIdle      time.Duration // quiet time on the bus before sending
+	FrameSpacing time.Duration // minimum time between two sent frames
+	MaxRate      int           // frames sent per second
+	MaxGap       time.Duration // pause within a frame after which it is dropped
+	MaxFrameAge  time.Duration // time receiving a frame may take before it is dropped
+
+	FailOnDisconnect bool // fail sends while the device is unplugged instead of queueing them
 }
 
 type MqttConfig struct {
-	Broker    string
-	RootTopic string
-	Enabled   bool
+	Broker          string
+	RootTopic       string
+	Enabled         bool
+	DiscoveryPrefix string
+
+	ClientID          string
+	Username          string
+	Password          string
+	KeepAlive         time.Duration
+	PersistentSession bool // keep subscriptions and queued messages on the broker between connections
+	PublishBuffer     int  // publishes kept while disconnected
+	LegacyMessages    bool // publish and read frames as LcnPacket JSON with base64 payload instead of message.Message
+}
+
+// CaptureConfig records the bus traffic into a capture or pcapng file, or replays a capture instead of opening the serial device.
+type CaptureConfig struct {
+	Record string  // capture file to write
+	Replay string  // capture file to read instead of the serial device
+	Speed  float64 // replay speed, 1 is real time, 0 as fast as possible
+	Pcapng string  // pcapng file or named pipe to export frames to
+}
+
+// SensorConfig is a frame the simulator sends periodically from a module.
+type SensorConfig struct {
+	Segment  int
+	Module   int
+	Target   int
+	Cmd      int
+	Payload  string // hex encoded
+	Interval time.Duration
+}
+
+// SimulatorConfig configures lcnSimulator, it emulates the modules of the devices section.
+type SimulatorConfig struct {
+	Link    string // symlink created to the pseudo terminal
+	Sensors []SensorConfig
+}
+
+// MetricsConfig enables the Prometheus metrics endpoint.
+type MetricsConfig struct {
+	Listen string // address like :9100, empty disables metrics
+}
+
+// PollConfig queries the status of all configured modules once connected and on an interval.
+type PollConfig struct {
+	Enabled  bool
+	Interval time.Duration // zero polls only once connected
+	Spacing  time.Duration // pause between the queries of two modules
+}
+
+type LcnConfig struct {
+	Source                int
+	Checksum              string // variant of lcn.Checksums used to validate received frames, yali by default
+	AcceptInvalidChecksum bool   // pass frames with a wrong checksum on flagged as invalid
+}
+
+// OutputConfig describes a module output, IDs are numbered from 1 like in LCN-PRO.
+type OutputConfig struct {
+	ID   int
+	Type string // relay, dimmer or shade
+	Name string
+	Down int // relais moving a shade down, ID is the relais moving it up
+
+	// time a shade takes to open or close fully, both enable positions
+	TravelUp   time.Duration
+	TravelDown time.Duration
+}
+
+type ModuleConfig struct {
+	ID      int
+	Name    string
+	Outputs []OutputConfig
+}
+
+type SegmentConfig struct {
+	ID      int
+	Modules []ModuleConfig
+}
+
+// DevicesConfig names the segments, modules and outputs of an installation.
+type DevicesConfig struct {
+	Segments []SegmentConfig
 }
 
 // Config struct.
 type Config struct {
-	Logger loggerConfig.Logger
-	Serial SerialConfig
-	Mqtt   MqttConfig
+	Logger    loggerConfig.Logger
+	Serial    SerialConfig
+	Mqtt      MqttConfig
+	Lcn       LcnConfig
+	Devices   DevicesConfig
+	Capture   CaptureConfig
+	Simulator SimulatorConfig
+	Metrics   MetricsConfig
+	Poll      PollConfig
 }
 
 // LoadConfig loads config file from given path.
@@ -0,0 +1,324 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/shade"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+const (
+	stateOn  = "ON"
+	stateOff = "OFF"
+
+	availabilityOnline  = "online"
+	availabilityOffline = "offline"
+)
+
+// Bridge publishes decoded module state on per output topics and translates commands on those topics into packets.
+//
+// Topics are <root>/module/<module>/relay/<output>/state and .../set, outputs are numbered 1-8.
+// Shades use <root>/module/<module>/shade/<up output>/state, .../set, .../position and .../set_position.
+type Bridge struct {
+	broker broker.Broker
+	port   serial.Port
+
+	rootTopic       string
+	source          byte
+	discoveryPrefix string
+	statsInterval   time.Duration
+	catalog         *monitor.Catalog
+	state           *state.Registry
+	shades          *shade.Shades
+	discovery       map[string]string
+
+	mutex    sync.Mutex
+	requests map[state.Key]*request // pending, by the outputs they switch
+}
+
+// request is sent in the background until it is acknowledged or superseded.
+type request struct {
+	cancel context.CancelFunc
+}
+
+// Run subscribes the command topics, commands are sent until ctx is done.
+func (b *Bridge) Run(ctx context.Context) {
+	subscribe := func(topic string, handler func(context.Context, string, string)) {
+		b.broker.
+			Topic(fmt.Sprintf("%s/%s", b.rootTopic, topic)).
+			SubscribeString(func(topic, data string) {
+				handler(ctx, topic, data)
+			})
+	}
+
+	subscribe("module/+/relay/+/set", b.onRelaisSet)
+	subscribe("module/+/shade/+/set", b.onShadeSet)
+	subscribe("module/+/shade/+/set_position", b.onShadePosition)
+
+	if dimmers := b.catalog.Devices().Dimmers; len(dimmers) > 0 {
+		log.Warnf("Ignoring %d dimmers, their commands are not decoded yet", len(dimmers))
+	}
+
+	b.runStatus(ctx)
+	b.runDiscovery()
+}
+
+// Handle updates the state from a packet seen on the bus, or sent by the bridge itself.
+func (b *Bridge) Handle(pkt *lcn.LcnPacket) {
+	if pkt.Invalid {
+		log.Debugf("Ignoring %s with invalid checksum", pkt.ToNiceString())
+
+		return
+	}
+
+	b.state.Handle(pkt)
+}
+
+// publishState publishes the outputs which changed and the shades driven by them.
+func (b *Bridge) publishState(events []state.Event) {
+	shades := make(map[*shade.Controller]bool)
+
+	for _, event := range events {
+		output := event.Output
+		module := byte(output.Module)
+
+		value := stateOff
+		if output.On {
+			value = stateOn
+		}
+
+		b.broker.
+			Topic(b.relaisTopic(module, output.Output, "state")).
+			PublishStringRetained(value)
+
+		if c, ok := b.shades.Owner(output.Segment, output.Module, output.Output); ok {
+			shades[c] = true
+		}
+	}
+
+	for c := range shades {
+		b.publishShade(c)
+	}
+}
+
+func (b *Bridge) relaisTopic(module byte, output int, suffix string) string {
+	return fmt.Sprintf("%s/module/%d/relay/%d/%s", b.rootTopic, module, output+1, suffix)
+}
+
+func (b *Bridge) onRelaisSet(ctx context.Context, topic, data string) {
+	module, output, err := parseOutputTopic(strings.TrimPrefix(topic, b.rootTopic+"/"), "relay")
+	if err != nil {
+		log.Errorf("Invalid relay topic %s: %s", topic, err)
+
+		return
+	}
+
+	if c, ok := b.shades.Owner(b.catalog.Segment(int(module)), int(module), output); ok {
+		log.Errorf("Refusing relay command on %s, the relay drives shade %s", topic, c.Shade().Name)
+
+		return
+	}
+
+	var action command.RelaisAction
+
+	switch strings.ToUpper(strings.TrimSpace(data)) {
+	case stateOn:
+		action = command.RelaisOn
+	case stateOff:
+		action = command.RelaisOff
+	case "TOGGLE":
+		action = command.RelaisToggle
+	default:
+		log.Errorf("Invalid relay command on %s: %s", topic, data)
+
+		return
+	}
+
+	cmd := new(command.RelaisCommand)
+	cmd.Outputs[output] = action
+
+	b.send(ctx, module, cmd)
+}
+
+// Interlock refuses frames from elsewhere, e.g. <root>/in, switching relais of a shade.
+func (b *Bridge) Interlock(pkt *lcn.LcnPacket) error {
+	return b.shades.Check(pkt)
+}
+
+// send sends a command to a module in its segment, see Send.
+func (b *Bridge) send(ctx context.Context, module byte, cmd command.Command) {
+	pkt, err := command.NewPacket(b.source, byte(b.catalog.Segment(int(module))), module, cmd)
+	if err != nil {
+		log.Errorf("Could not build packet for %s: %s", cmd, err)
+
+		return
+	}
+
+	log.Infof("Sending %s to module %d", cmd, module)
+
+	b.Send(ctx, pkt)
+}
+
+// Send sends a packet in the background. Commands expecting a response are retried until the module
+// acknowledges them and update the state once it did; a newer command for the same outputs supersedes
+// them. Other packets are sent once, the state follows the report of the module.
+func (b *Bridge) Send(ctx context.Context, pkt *lcn.LcnPacket) {
+	buf, err := pkt.Serialize()
+	if err != nil {
+		log.Errorf("Could not serialize LCN %s: %s", pkt.ToNiceString(), err)
+
+		return
+	}
+
+	cmd, err := command.Decode(pkt)
+	if err != nil {
+		cmd = nil
+	}
+
+	ctx, done := b.supersede(ctx, b.targets(pkt, cmd))
+
+	go func() {
+		defer done()
+
+		if cmd == nil || !command.Idempotent(cmd) {
+			if err := b.port.Send(ctx, buf); err != nil {
+				log.Errorf("Could not send %s: %s", pkt.ToNiceString(), err)
+			}
+
+			return
+		}
+
+		if _, err := b.port.Request(ctx, buf, command.ExpectResponse(pkt)); err != nil {
+			if ctx.Err() != nil {
+				log.Debugf("Dropped %s: %s", cmd, err)
+			} else {
+				log.Errorf("Module %d did not acknowledge %s: %s", pkt.Dst, cmd, err)
+			}
+
+			return
+		}
+
+		b.Handle(pkt)
+	}()
+}
+
+// supersede cancels the pending requests switching any of the targets and registers a new one,
+// done unregisters it.
+func (b *Bridge) supersede(ctx context.Context, targets []state.Key) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &request{cancel: cancel}
+
+	b.mutex.Lock()
+	for _, t := range targets {
+		if previous, ok := b.requests[t]; ok {
+			previous.cancel()
+		}
+
+		b.requests[t] = r
+	}
+	b.mutex.Unlock()
+
+	return ctx, func() {
+		b.mutex.Lock()
+		for _, t := range targets {
+			if b.requests[t] == r {
+				delete(b.requests, t)
+			}
+		}
+		b.mutex.Unlock()
+
+		cancel()
+	}
+}
+
+// targets returns the outputs a command switches.
+func (b *Bridge) targets(pkt *lcn.LcnPacket, cmd command.Command) []state.Key {
+	// segment 0 is the own segment of the module
+	segment := int(pkt.Seg)
+	if segment == 0 {
+		segment = b.catalog.Segment(int(pkt.Dst))
+	}
+
+	relaisCmd, ok := cmd.(*command.RelaisCommand)
+	if !ok {
+		return nil
+	}
+
+	var targets []state.Key
+
+	for output, action := range relaisCmd.Outputs {
+		if action != command.RelaisNoChange {
+			targets = append(targets, state.Key{Segment: segment, Module: int(pkt.Dst), Output: output})
+		}
+	}
+
+	return targets
+}
+
+// parseOutputTopic parses "module/<module>/<kind>/<output>/..." into a module ID and a zero based output index.
+func parseOutputTopic(topic, kind string) (byte, int, error) {
+	const minParts = 4
+
+	parts := strings.Split(topic, "/")
+	if len(parts) < minParts || parts[0] != "module" || parts[2] != kind {
+		return 0, 0, fmt.Errorf("expected module/<module>/%s/<output>", kind)
+	}
+
+	module, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid module %q: %w", parts[1], err)
+	}
+
+	output, err := strconv.Atoi(parts[3])
+	if err != nil || output < 1 || output > 8 {
+		return 0, 0, fmt.Errorf("invalid output %q", parts[3])
+	}
+
+	return byte(module), output - 1, nil
+}
+
+func New(b broker.Broker, p serial.Port, options ...Option) *Bridge {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	bridge := &Bridge{
+		broker:          b,
+		port:            p,
+		rootTopic:       config.rootTopic,
+		source:          config.source,
+		discoveryPrefix: config.discoveryPrefix,
+		statsInterval:   config.statsInterval,
+		catalog:         config.catalog,
+		state:           config.state,
+		requests:        make(map[state.Key]*request),
+	}
+
+	if bridge.state == nil {
+		bridge.state = state.NewRegistry(state.Catalog(config.catalog))
+	}
+
+	// the shades learn about their relais before the state is published
+	bridge.shades = shade.New(config.catalog, bridge.state, func(ctx context.Context, s monitor.Shade, cmd *command.RelaisCommand) {
+		bridge.send(ctx, byte(s.Module), cmd)
+	}, config.shadeOptions...)
+
+	bridge.discovery = bridge.discoveryConfigs(config.catalog.Devices())
+
+	bridge.state.Subscribe(bridge.publishState)
+
+	return bridge
+}
@@ -0,0 +1,300 @@
+package bridge_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/bridge"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+type fakeBroker struct {
+	mutex         sync.Mutex
+	retained      map[string]string
+	subscriptions map[string]broker.StringCallbackFunction
+	onConnect     []func()
+}
+
+type fakeTopic struct {
+	broker *fakeBroker
+	topic  string
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		retained:      make(map[string]string),
+		subscriptions: make(map[string]broker.StringCallbackFunction),
+	}
+}
+
+func (b *fakeBroker) Run(context.Context, context.CancelFunc) {}
+
+func (b *fakeBroker) Topic(topic string) broker.Topic {
+	return &fakeTopic{broker: b, topic: topic}
+}
+
+func (b *fakeBroker) OnConnect(callback func()) {
+	b.onConnect = append(b.onConnect, callback)
+}
+
+func (b *fakeBroker) Close() {}
+
+func (b *fakeBroker) Stats() broker.Stats {
+	return broker.Stats{}
+}
+
+func (b *fakeBroker) connect() {
+	for _, callback := range b.onConnect {
+		callback()
+	}
+}
+
+func (b *fakeBroker) get(topic string) (string, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s, ok := b.retained[topic]
+
+	return s, ok
+}
+
+func (t *fakeTopic) PublishString(string) {}
+
+func (t *fakeTopic) PublishStringRetained(s string) {
+	t.broker.mutex.Lock()
+	defer t.broker.mutex.Unlock()
+
+	t.broker.retained[t.topic] = s
+}
+
+func (t *fakeTopic) Publish(interface{}) {}
+
+func (t *fakeTopic) Subscribe(interface{}, broker.CallbackFunction) {}
+
+func (t *fakeTopic) SubscribeString(callback broker.StringCallbackFunction) {
+	t.broker.subscriptions[t.topic] = callback
+}
+
+type fakePort struct {
+	sent chan []byte
+
+	mutex      sync.Mutex
+	unanswered int         // requests left without a response until they are cancelled
+	cancelled  chan []byte // requests given up
+}
+
+func (p *fakePort) Run(context.Context, context.CancelFunc, chunker.EjectFunc) {}
+
+func (p *fakePort) Send(_ context.Context, buf []byte) error {
+	p.sent <- buf
+
+	return nil
+}
+
+func (p *fakePort) QueueDepth() int {
+	return len(p.sent)
+}
+
+func (p *fakePort) State() serial.State {
+	return serial.StateConnected
+}
+
+func (p *fakePort) OnStateChange(callback serial.StateCallback) {
+	callback(serial.StateConnected)
+}
+
+func (p *fakePort) Stats() serial.Stats {
+	return serial.Stats{Sent: 1}
+}
+
+func (p *fakePort) Request(ctx context.Context, buf []byte, _ serial.Matcher) (packet.Packet, error) {
+	if err := p.Send(ctx, buf); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	unanswered := p.unanswered > 0
+	if unanswered {
+		p.unanswered--
+	}
+	p.mutex.Unlock()
+
+	if unanswered {
+		<-ctx.Done()
+		p.cancelled <- buf
+
+		return nil, ctx.Err()
+	}
+
+	return nil, nil
+}
+
+// eventually asserts that topic ends up with the retained state.
+func eventually(t *testing.T, b *fakeBroker, topic, expected string) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		state, _ := b.get(topic)
+
+		return state == expected
+	}, time.Second, time.Millisecond, "%s is not %s", topic, expected)
+}
+
+func newBridge() (*bridge.Bridge, *fakeBroker, *fakePort) {
+	b := newFakeBroker()
+	p := &fakePort{sent: make(chan []byte, 10), cancelled: make(chan []byte, 10)}
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{{
+			Modules: []config.ModuleConfig{
+				{ID: 31, Outputs: []config.OutputConfig{{ID: 1, Type: "shade", Name: "Shade", Down: 2}}},
+				{ID: 33, Outputs: []config.OutputConfig{{ID: 1, Type: "relay", Name: "Light"}}},
+			},
+		}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	br := bridge.New(b, p,
+		bridge.RootTopic("lcn"),
+		bridge.DiscoveryPrefix("homeassistant"),
+		bridge.Catalog(catalog),
+	)
+	br.Run(context.Background())
+
+	return br, b, p
+}
+
+func TestRelaisState(t *testing.T) {
+	br, b, _ := newBridge()
+
+	br.Handle(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}})
+
+	state, ok := b.get("lcn/module/33/relay/1/state")
+	assert.True(t, ok)
+	assert.Equal(t, "ON", state)
+
+	state, ok = b.get("lcn/module/33/relay/2/state")
+	assert.True(t, ok)
+	assert.Equal(t, "OFF", state)
+
+	br.Handle(&lcn.LcnPacket{Src: 11, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x01}})
+
+	state, _ = b.get("lcn/module/33/relay/1/state")
+	assert.Equal(t, "OFF", state)
+
+	// frames with an invalid checksum are not trusted
+	br.Handle(&lcn.LcnPacket{Src: 11, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x02}, Invalid: true})
+
+	state, _ = b.get("lcn/module/33/relay/1/state")
+	assert.Equal(t, "OFF", state)
+}
+
+func TestSerialState(t *testing.T) {
+	_, b, _ := newBridge()
+
+	state, _ := b.get("lcn/bridge/serial")
+	assert.Equal(t, "online", state)
+}
+
+func TestBridgeState(t *testing.T) {
+	br, b, _ := newBridge()
+
+	b.connect()
+
+	state, _ := b.get("lcn/bridge/state")
+	assert.Equal(t, "online", state)
+
+	br.Close()
+
+	state, _ = b.get("lcn/bridge/state")
+	assert.Equal(t, "offline", state)
+}
+
+func TestRelaisSet(t *testing.T) {
+	_, b, p := newBridge()
+
+	b.subscriptions["lcn/module/+/relay/+/set"]("lcn/module/33/relay/8/set", "toggle")
+
+	buf := <-p.sent
+	pkt, err := lcn.Deserialize(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(33), pkt.(*lcn.LcnPacket).Dst)
+	assert.Equal(t, []byte{0x00, 0x80}, pkt.(*lcn.LcnPacket).Payload)
+}
+
+func TestRelaisSetPublishedOnceAcknowledged(t *testing.T) {
+	_, b, p := newBridge()
+
+	p.unanswered = 1
+
+	b.subscriptions["lcn/module/+/relay/+/set"]("lcn/module/33/relay/1/set", "ON")
+	on := <-p.sent
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok := b.get("lcn/module/33/relay/1/state")
+	assert.False(t, ok, "not published before the module acknowledged")
+
+	// the newer command supersedes the unanswered one
+	b.subscriptions["lcn/module/+/relay/+/set"]("lcn/module/33/relay/1/set", "OFF")
+
+	assert.Equal(t, on, <-p.cancelled)
+	<-p.sent
+
+	eventually(t, b, "lcn/module/33/relay/1/state", "OFF")
+}
+
+func TestShadeInterlock(t *testing.T) {
+	br, b, p := newBridge()
+
+	b.subscriptions["lcn/module/+/relay/+/set"]("lcn/module/31/relay/2/set", "ON")
+	assert.Empty(t, p.sent)
+
+	assert.Error(t, br.Interlock(&lcn.LcnPacket{Src: 1, Dst: 31, Cmd: 0x13, Payload: []byte{0x02, 0x00}}))
+	assert.NoError(t, br.Interlock(&lcn.LcnPacket{Src: 1, Dst: 31, Cmd: 0x13, Payload: []byte{0x04, 0x00}}))
+}
+
+func TestShadeSet(t *testing.T) {
+	_, b, p := newBridge()
+
+	b.subscriptions["lcn/module/+/shade/+/set"]("lcn/module/31/shade/1/set", "CLOSE")
+
+	buf := <-p.sent
+	pkt, err := lcn.Deserialize(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x03, 0x01}, pkt.(*lcn.LcnPacket).Payload)
+
+	state, _ := b.get("lcn/module/31/shade/1/state")
+	assert.Equal(t, "closing", state)
+}
+
+func TestDiscovery(t *testing.T) {
+	_, b, _ := newBridge()
+
+	b.connect()
+
+	light, ok := b.get("homeassistant/light/lcn/lcn_33_1/config")
+	assert.True(t, ok)
+	assert.Contains(t, light, `"command_topic":"lcn/module/33/relay/1/set"`)
+
+	_, ok = b.get("homeassistant/cover/lcn/lcn_31_1/config")
+	assert.True(t, ok)
+
+	b.subscriptions["homeassistant/+/lcn/+/config"]("homeassistant/light/lcn/lcn_33_2/config", "{}")
+
+	removed, ok := b.get("homeassistant/light/lcn/lcn_33_2/config")
+	assert.True(t, ok)
+	assert.Equal(t, "", removed)
+}
@@ -0,0 +1,170 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+)
+
+//nolint:gochecknoglobals
+var invalidNodeIDCharacters = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+type availability struct {
+	Topic string `json:"topic"`
+}
+
+type discoveryConfig struct {
+	Name             string          `json:"name"`
+	UniqueID         string          `json:"unique_id"`
+	Device           discoveryDevice `json:"device"`
+	Availability     []availability  `json:"availability"`
+	AvailabilityMode string          `json:"availability_mode"`
+}
+
+type lightConfig struct {
+	discoveryConfig
+	CommandTopic string `json:"command_topic"`
+	StateTopic   string `json:"state_topic"`
+	PayloadOn    string `json:"payload_on"`
+	PayloadOff   string `json:"payload_off"`
+}
+
+type coverConfig struct {
+	discoveryConfig
+	CommandTopic string `json:"command_topic"`
+	StateTopic   string `json:"state_topic"`
+	PayloadOpen  string `json:"payload_open"`
+	PayloadClose string `json:"payload_close"`
+	PayloadStop  string `json:"payload_stop"`
+	StateOpening string `json:"state_opening"`
+	StateClosing string `json:"state_closing"`
+	StateStopped string `json:"state_stopped"`
+
+	// with travel times only
+	PositionTopic    string `json:"position_topic,omitempty"`
+	SetPositionTopic string `json:"set_position_topic,omitempty"`
+}
+
+// discoveryConfigs builds the retained Home Assistant discovery messages by topic,
+// dimmer outputs are left out as long as there is no command encoding for them.
+func (b *Bridge) discoveryConfigs(devices monitor.Devices) map[string]string {
+	configs := make(map[string]string)
+
+	add := func(component string, device monitor.Device, config interface{}) {
+		payload, err := json.Marshal(config)
+		if err != nil {
+			log.Errorf("Cannot marshal discovery config for %s: %s", device.Name, err)
+
+			return
+		}
+
+		configs[b.discoveryTopic(component, b.objectID(device))] = string(payload)
+	}
+
+	for _, light := range devices.Lights {
+		add("light", light.Device, lightConfig{
+			discoveryConfig: b.discoveryConfig(light.Device),
+			CommandTopic:    b.relaisTopic(byte(light.Module), light.Output, "set"),
+			StateTopic:      b.relaisTopic(byte(light.Module), light.Output, "state"),
+			PayloadOn:       stateOn,
+			PayloadOff:      stateOff,
+		})
+	}
+
+	for _, shade := range devices.Shades {
+		cover := coverConfig{
+			discoveryConfig: b.discoveryConfig(shade.Device),
+			CommandTopic:    b.shadeTopic(byte(shade.Module), shade.Output, "set"),
+			StateTopic:      b.shadeTopic(byte(shade.Module), shade.Output, "state"),
+			PayloadOpen:     shadeOpen,
+			PayloadClose:    shadeClose,
+			PayloadStop:     shadeStop,
+			StateOpening:    state.ShadeOpening.String(),
+			StateClosing:    state.ShadeClosing.String(),
+			StateStopped:    state.ShadeStopped.String(),
+		}
+
+		if shade.Travels() {
+			cover.PositionTopic = b.shadeTopic(byte(shade.Module), shade.Output, "position")
+			cover.SetPositionTopic = b.shadeTopic(byte(shade.Module), shade.Output, "set_position")
+		}
+
+		add("cover", shade.Device, cover)
+	}
+
+	return configs
+}
+
+func (b *Bridge) discoveryConfig(device monitor.Device) discoveryConfig {
+	return discoveryConfig{
+		Name:     device.Name,
+		UniqueID: b.objectID(device),
+		Device: discoveryDevice{
+			Identifiers:  []string{fmt.Sprintf("%s_module_%d", b.nodeID(), device.Module)},
+			Name:         b.catalog.ModuleName(device.Segment, device.Module),
+			Manufacturer: "Issendorff",
+			Model:        "LCN module",
+		},
+		Availability: []availability{
+			{Topic: StateTopic(b.rootTopic)},
+			{Topic: b.serialTopic()},
+		},
+		AvailabilityMode: "all",
+	}
+}
+
+func (b *Bridge) nodeID() string {
+	return invalidNodeIDCharacters.ReplaceAllString(b.rootTopic, "_")
+}
+
+func (b *Bridge) objectID(device monitor.Device) string {
+	return fmt.Sprintf("%s_%d_%d", b.nodeID(), device.Module, device.Output+1)
+}
+
+func (b *Bridge) discoveryTopic(component, objectID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/config", b.discoveryPrefix, component, b.nodeID(), objectID)
+}
+
+// publishDiscovery publishes all discovery messages, it is called on every connect to the broker.
+func (b *Bridge) publishDiscovery() {
+	for topic, payload := range b.discovery {
+		b.broker.Topic(topic).PublishStringRetained(payload)
+	}
+}
+
+// onDiscovery removes retained discovery messages of devices which are no longer configured.
+func (b *Bridge) onDiscovery(topic, data string) {
+	if data == "" {
+		return
+	}
+
+	if _, ok := b.discovery[topic]; ok {
+		return
+	}
+
+	log.Infof("Removing discovery config %s", topic)
+	b.broker.Topic(topic).PublishStringRetained("")
+}
+
+func (b *Bridge) runDiscovery() {
+	if b.discoveryPrefix == "" {
+		return
+	}
+
+	b.broker.OnConnect(b.publishDiscovery)
+	b.broker.
+		Topic(strings.Join([]string{b.discoveryPrefix, "+", b.nodeID(), "+", "config"}, "/")).
+		SubscribeString(b.onDiscovery)
+}
@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/shade"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		rootTopic       string
+		source          byte
+		discoveryPrefix string
+		statsInterval   time.Duration
+		catalog         *monitor.Catalog
+		state           *state.Registry
+		shadeOptions    []shade.Option
+	}
+)
+
+func RootTopic(rootTopic string) Option {
+	return func(c *Config) {
+		c.rootTopic = rootTopic
+	}
+}
+
+// Source sets the module ID used as source of packets sent by the bridge.
+func Source(source byte) Option {
+	return func(c *Config) {
+		c.source = source
+	}
+}
+
+// DiscoveryPrefix enables Home Assistant MQTT discovery below the given prefix, usually "homeassistant".
+func DiscoveryPrefix(prefix string) Option {
+	return func(c *Config) {
+		c.discoveryPrefix = prefix
+	}
+}
+
+// Catalog sets the devices announced via discovery and controlled via their own topics.
+func Catalog(catalog *monitor.Catalog) Option {
+	return func(c *Config) {
+		c.catalog = catalog
+	}
+}
+
+// State shares the registry of output states, by default the bridge keeps its own fed by Handle.
+func State(registry *state.Registry) Option {
+	return func(c *Config) {
+		c.state = registry
+	}
+}
+
+// ShadeOptions configures the shade controllers, e.g. the pause before reversing.
+func ShadeOptions(options ...shade.Option) Option {
+	return func(c *Config) {
+		c.shadeOptions = append(c.shadeOptions, options...)
+	}
+}
+
+// StatsInterval sets how often counters are published on <root>/bridge/stats, 0 disables them.
+func StatsInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.statsInterval = interval
+	}
+}
+
+func newDefaultConfig() *Config {
+	catalog, _ := monitor.NewCatalog(config.DevicesConfig{})
+
+	return &Config{
+		catalog:   catalog,
+		rootTopic: "lcn",
+		source:    1,
+
+		statsInterval: time.Minute,
+	}
+}
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MyChaOS87/reverseLCN/internal/shade"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+)
+
+const (
+	shadeOpen  = "OPEN"
+	shadeClose = "CLOSE"
+	shadeStop  = "STOP"
+)
+
+func (b *Bridge) shadeTopic(module byte, output int, suffix string) string {
+	return fmt.Sprintf("%s/module/%d/shade/%d/%s", b.rootTopic, module, output+1, suffix)
+}
+
+// publishShade publishes the direction and, with travel times, the estimated position of a shade.
+func (b *Bridge) publishShade(c *shade.Controller) {
+	s := c.Shade()
+
+	b.broker.
+		Topic(b.shadeTopic(byte(s.Module), s.Output, "state")).
+		PublishStringRetained(c.Moving().String())
+
+	if position, known := c.Position(); known {
+		b.broker.
+			Topic(b.shadeTopic(byte(s.Module), s.Output, "position")).
+			PublishStringRetained(strconv.FormatFloat(position, 'f', 0, 64))
+	}
+}
+
+// controller returns the shade of a topic below <root>/module/+/shade/+/.
+func (b *Bridge) controller(topic string) (*shade.Controller, bool) {
+	module, output, err := parseOutputTopic(strings.TrimPrefix(topic, b.rootTopic+"/"), "shade")
+	if err != nil {
+		log.Errorf("Invalid shade topic %s: %s", topic, err)
+
+		return nil, false
+	}
+
+	c, ok := b.shades.Get(b.catalog.Segment(int(module)), int(module), output)
+	if !ok {
+		log.Errorf("No shade configured for %s", topic)
+	}
+
+	return c, ok
+}
+
+func (b *Bridge) onShadeSet(ctx context.Context, topic, data string) {
+	c, ok := b.controller(topic)
+	if !ok {
+		return
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(data)) {
+	case shadeOpen:
+		c.Open(ctx)
+	case shadeClose:
+		c.Close(ctx)
+	case shadeStop:
+		c.Stop(ctx)
+	default:
+		log.Errorf("Invalid shade command on %s: %s", topic, data)
+	}
+}
+
+func (b *Bridge) onShadePosition(ctx context.Context, topic, data string) {
+	c, ok := b.controller(topic)
+	if !ok {
+		return
+	}
+
+	position, err := strconv.ParseFloat(strings.TrimSpace(data), 64)
+	if err != nil {
+		log.Errorf("Invalid shade position on %s: %s", topic, data)
+
+		return
+	}
+
+	if err := c.SetPosition(ctx, position); err != nil {
+		log.Errorf("Cannot move %s to %s: %s", c.Shade().Name, data, err)
+	}
+}
@@ -0,0 +1,103 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+type bridgeStats struct {
+	Serial      string `json:"serial"`
+	Received    uint64 `json:"received"`
+	Sent        uint64 `json:"sent"`
+	WriteErrors uint64 `json:"writeErrors"`
+	Connects    uint64 `json:"connects"`
+	QueueDepth  int    `json:"queueDepth"`
+
+	DroppedBytes     uint64 `json:"droppedBytes"`
+	ChecksumFailures uint64 `json:"checksumFailures"`
+	Resyncs          uint64 `json:"resyncs"`
+}
+
+// StateTopic is the topic carrying online/offline of the bridge itself, use it for the last will.
+func StateTopic(rootTopic string) string {
+	return fmt.Sprintf("%s/bridge/state", rootTopic)
+}
+
+func (b *Bridge) serialTopic() string {
+	return fmt.Sprintf("%s/bridge/serial", b.rootTopic)
+}
+
+func (b *Bridge) statsTopic() string {
+	return fmt.Sprintf("%s/bridge/stats", b.rootTopic)
+}
+
+func (b *Bridge) publishOnline() {
+	b.broker.Topic(StateTopic(b.rootTopic)).PublishStringRetained(availabilityOnline)
+}
+
+// onSerialState publishes the availability of the LCN bus.
+func (b *Bridge) onSerialState(state serial.State) {
+	availability := availabilityOffline
+	if state == serial.StateConnected {
+		availability = availabilityOnline
+	}
+
+	b.broker.Topic(b.serialTopic()).PublishStringRetained(availability)
+}
+
+func (b *Bridge) publishStats() {
+	stats := b.port.Stats()
+
+	payload, err := json.Marshal(bridgeStats{
+		Serial:      b.port.State().String(),
+		Received:    stats.Received,
+		Sent:        stats.Sent,
+		WriteErrors: stats.WriteErrors,
+		Connects:    stats.Connects,
+		QueueDepth:  stats.QueueDepth,
+
+		DroppedBytes:     stats.DroppedBytes,
+		ChecksumFailures: stats.ChecksumFailures,
+		Resyncs:          stats.Resyncs,
+	})
+	if err != nil {
+		log.Errorf("Cannot marshal bridge stats: %s", err)
+
+		return
+	}
+
+	b.broker.Topic(b.statsTopic()).PublishStringRetained(string(payload))
+}
+
+func (b *Bridge) runStatus(ctx context.Context) {
+	b.broker.OnConnect(b.publishOnline)
+	b.port.OnStateChange(b.onSerialState)
+
+	if b.statsInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(b.statsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				b.publishStats()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close marks the bridge offline, call it before closing the broker.
+func (b *Bridge) Close() {
+	b.broker.Topic(StateTopic(b.rootTopic)).PublishStringRetained(availabilityOffline)
+}
@@ -0,0 +1,172 @@
+// Package checksum compares checksum variants against frames seen on the bus.
+package checksum
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+const checksumIndex = 2
+
+// Counts are the frames of a group and how many of them each variant failed on.
+type Counts struct {
+	Frames     int
+	Mismatches map[string]int // by variant
+}
+
+func (c *Counts) add(mismatches []string) {
+	c.Frames++
+
+	for _, variant := range mismatches {
+		c.Mismatches[variant]++
+	}
+}
+
+// Verifier runs checksum variants over frames, grouping the results by length, Info byte and command.
+type Verifier struct {
+	variants  []string
+	checksums map[string]lcn.ChecksumFunc
+
+	Total     Counts
+	ByLength  map[int]*Counts
+	ByInfo    map[byte]*Counts
+	ByCommand map[byte]*Counts
+}
+
+func NewVerifier(variants ...string) (*Verifier, error) {
+	v := &Verifier{
+		checksums: make(map[string]lcn.ChecksumFunc),
+		Total:     Counts{Mismatches: make(map[string]int)},
+		ByLength:  make(map[int]*Counts),
+		ByInfo:    make(map[byte]*Counts),
+		ByCommand: make(map[byte]*Counts),
+	}
+
+	for _, name := range variants {
+		checksum, err := lcn.ChecksumByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		v.variants = append(v.variants, name)
+		v.checksums[name] = checksum
+	}
+
+	return v, nil
+}
+
+// Add checks a frame as read from the bus.
+func (v *Verifier) Add(frame []byte) {
+	if len(frame) < lcn.MIN_LCN_PACKET_LENGTH {
+		return
+	}
+
+	var mismatches []string
+
+	for _, name := range v.variants {
+		if v.checksums[name](frame) != frame[checksumIndex] {
+			mismatches = append(mismatches, name)
+		}
+	}
+
+	v.Total.add(mismatches)
+	group(v.ByLength, len(frame)).add(mismatches)
+	group(v.ByInfo, frame[1]).add(mismatches)
+	group(v.ByCommand, frame[5]).add(mismatches)
+}
+
+// AddPacket checks a deserialized frame, keeping its received checksum.
+func (v *Verifier) AddPacket(pkt *lcn.LcnPacket) error {
+	frame, err := lcn.NewCodec().Serialize(pkt)
+	if err != nil {
+		return err
+	}
+
+	frame[1] = pkt.Info
+	frame[checksumIndex] = pkt.Checksum
+
+	v.Add(frame)
+
+	return nil
+}
+
+func group[K comparable](groups map[K]*Counts, key K) *Counts {
+	counts, ok := groups[key]
+	if !ok {
+		counts = &Counts{Mismatches: make(map[string]int)}
+		groups[key] = counts
+	}
+
+	return counts
+}
+
+// Report writes the mismatches of each variant as tables.
+func (v *Verifier) Report(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight) //nolint:gomnd
+
+	v.writeHeader(table, "")
+	v.writeRow(table, "all", &v.Total)
+
+	v.writeHeader(table, "length")
+
+	for _, length := range sortedKeys(v.ByLength) {
+		v.writeRow(table, fmt.Sprintf("%d", length), v.ByLength[length])
+	}
+
+	v.writeHeader(table, "info")
+
+	for _, info := range sortedKeys(v.ByInfo) {
+		v.writeRow(table, fmt.Sprintf("%02x", info), v.ByInfo[info])
+	}
+
+	v.writeHeader(table, "command")
+
+	for _, cmd := range sortedKeys(v.ByCommand) {
+		v.writeRow(table, command.Name(cmd), v.ByCommand[cmd])
+	}
+
+	return errors.Wrap(table.Flush(), "cannot write report")
+}
+
+func (v *Verifier) writeHeader(w io.Writer, name string) {
+	if name != "" {
+		fmt.Fprintln(w, "\t")
+	}
+
+	fmt.Fprintf(w, "%s\tframes\t", name)
+
+	for _, variant := range v.variants {
+		fmt.Fprintf(w, "%s\t", variant)
+	}
+
+	fmt.Fprintln(w)
+}
+
+func (v *Verifier) writeRow(w io.Writer, name string, counts *Counts) {
+	fmt.Fprintf(w, "%s\t%d\t", name, counts.Frames)
+
+	for _, variant := range v.variants {
+		mismatches := counts.Mismatches[variant]
+		fmt.Fprintf(w, "%d (%.1f%%)\t", mismatches, 100*float64(mismatches)/float64(counts.Frames)) //nolint:gomnd
+	}
+
+	fmt.Fprintln(w)
+}
+
+func sortedKeys[K int | byte](groups map[K]*Counts) []K {
+	keys := make([]K, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
@@ -0,0 +1,39 @@
+package checksum_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/checksum"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+func TestVerifier(t *testing.T) {
+	_, err := checksum.NewVerifier("yali", "crc32")
+	assert.ErrorIs(t, err, lcn.ErrUnknownChecksum)
+
+	v, err := checksum.NewVerifier("yali", "sum")
+	require.NoError(t, err)
+
+	v.Add([]byte{0xa8, 0x06, 0x75, 0x00, 0x04, 0x68, 0x30, 0x00}) // valid
+	v.Add([]byte{0xa8, 0x06, 0x76, 0x00, 0x04, 0x68, 0x30, 0x00}) // checksum off by one
+	v.Add([]byte{0x80, 0x00, 0x8b, 0x02, 0x04, 0x05})             // matching the sum only
+	v.Add([]byte{0x80, 0x00})                                     // too short
+
+	require.NoError(t, v.AddPacket(&lcn.LcnPacket{
+		Src: 0x1f, Info: 0x4e, Checksum: 0x66, Seg: 0x4, Dst: 0x4, Cmd: 0x22,
+		Payload: []byte{0x1, 0x0, 0x5, 0x38, 0x13, 0x3, 0xb, 0x17, 0x5, 0x3c, 0x0, 0x0, 0x1, 0x41},
+	}))
+
+	assert.Equal(t, checksum.Counts{Frames: 4, Mismatches: map[string]int{"yali": 2, "sum": 3}}, v.Total)
+	assert.Equal(t, checksum.Counts{Frames: 2, Mismatches: map[string]int{"yali": 1, "sum": 2}}, *v.ByLength[8])
+	assert.Equal(t, checksum.Counts{Frames: 1, Mismatches: map[string]int{"yali": 1}}, *v.ByInfo[0x00])
+	assert.Equal(t, checksum.Counts{Frames: 1, Mismatches: map[string]int{"sum": 1}}, *v.ByCommand[0x22])
+
+	var report strings.Builder
+	require.NoError(t, v.Report(&report))
+	assert.Regexp(t, `statusReport\s+2\s+1 \(50.0%\)\s+2 \(100.0%\)`, report.String())
+}
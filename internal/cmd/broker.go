@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker/mqtt"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker/null"
+)
+
+// NewBroker creates the MQTT broker described by cfg, or a null broker if MQTT is disabled.
+func NewBroker(cfg *config.MqttConfig, options ...mqtt.Option) broker.Broker {
+	if !cfg.Enabled {
+		return null.NewBroker()
+	}
+
+	mqttOptions := []mqtt.Option{
+		mqtt.Broker(cfg.Broker),
+		mqtt.CleanSession(!cfg.PersistentSession),
+	}
+
+	if cfg.ClientID != "" {
+		mqttOptions = append(mqttOptions, mqtt.ClientID(cfg.ClientID))
+	}
+
+	if cfg.Username != "" {
+		mqttOptions = append(mqttOptions, mqtt.Credentials(cfg.Username, cfg.Password))
+	}
+
+	if cfg.PublishBuffer > 0 {
+		mqttOptions = append(mqttOptions, mqtt.PublishBuffer(cfg.PublishBuffer))
+	}
+
+	if cfg.KeepAlive > 0 {
+		mqttOptions = append(mqttOptions, mqtt.KeepAlive(cfg.KeepAlive))
+	}
+
+	return mqtt.NewBroker(append(mqttOptions, options...)...)
+}
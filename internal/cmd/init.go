@@ -53,3 +53,17 @@ func Init() (ctx context.Context, cancel context.CancelFunc, cfg *config.Config)
 
 	return ctx, cancel, cfg
 }
+
+// KeepLogOffScreen moves logging from stdout to output for commands drawing on the terminal.
+func KeepLogOffScreen(cfg *config.Config, output string) {
+	if cfg.Logger.Output != "" && cfg.Logger.Output != "stdout" {
+		return
+	}
+
+	cfg.Logger.Output = output
+
+	appLogger := logger.NewLogger(&cfg.Logger)
+	appLogger.InitLogger()
+
+	logger.SetDefaultLogger(appLogger)
+}
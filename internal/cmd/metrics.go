@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/metrics"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+// FrameCounter counts received LCN frames by command, source and segment.
+type FrameCounter struct {
+	frames *metrics.CounterVec
+}
+
+func (c *FrameCounter) Count(pkt *lcn.LcnPacket) {
+	c.frames.Inc(command.Name(pkt.Cmd), strconv.Itoa(int(pkt.Src)), strconv.Itoa(int(pkt.Seg)))
+}
+
+// RegisterPortMetrics registers the health of the bus connection.
+func RegisterPortMetrics(registry *metrics.Registry, port serial.Port) *FrameCounter {
+	counter := func(name, help string, value func(serial.Stats) uint64) {
+		registry.CounterFunc(name, help, func() float64 {
+			return float64(value(port.Stats()))
+		})
+	}
+
+	counter("lcn_serial_sent_frames_total", "Frames written to the bus.",
+		func(s serial.Stats) uint64 { return s.Sent })
+	counter("lcn_serial_write_errors_total", "Failed writes to the bus.",
+		func(s serial.Stats) uint64 { return s.WriteErrors })
+	counter("lcn_serial_connects_total", "Successful opens of the bus connection.",
+		func(s serial.Stats) uint64 { return s.Connects })
+	counter("lcn_serial_dropped_bytes_total", "Received bytes not belonging to a frame.",
+		func(s serial.Stats) uint64 { return s.DroppedBytes })
+	counter("lcn_serial_checksum_errors_total", "Received frames with invalid checksum.",
+		func(s serial.Stats) uint64 { return s.ChecksumFailures })
+	counter("lcn_serial_resyncs_total", "Times the frame boundaries were lost.",
+		func(s serial.Stats) uint64 { return s.Resyncs })
+
+	registry.GaugeFunc("lcn_serial_queue_depth", "Frames waiting to be sent.", func() float64 {
+		return float64(port.QueueDepth())
+	})
+	registry.GaugeFunc("lcn_serial_connected", "1 while the bus connection is open.", func() float64 {
+		if port.State() == serial.StateConnected {
+			return 1
+		}
+
+		return 0
+	})
+
+	return &FrameCounter{
+		frames: registry.Counter("lcn_frames_received_total", "Frames received from the bus.",
+			"command", "source", "segment"),
+	}
+}
+
+// RegisterBrokerMetrics registers the health of the MQTT connection.
+func RegisterBrokerMetrics(registry *metrics.Registry, b broker.Broker) {
+	counter := func(name, help string, value func(broker.Stats) uint64) {
+		registry.CounterFunc(name, help, func() float64 {
+			return float64(value(b.Stats()))
+		})
+	}
+
+	counter("lcn_mqtt_published_total", "Messages published to the MQTT broker.",
+		func(s broker.Stats) uint64 { return s.Published })
+	counter("lcn_mqtt_publish_failures_total", "Publishes failed or attempted while disconnected.",
+		func(s broker.Stats) uint64 { return s.PublishFailures })
+	counter("lcn_mqtt_dropped_total", "Publishes dropped because the buffer was full.",
+		func(s broker.Stats) uint64 { return s.Dropped })
+	counter("lcn_mqtt_connects_total", "Connects to the MQTT broker, including reconnects.",
+		func(s broker.Stats) uint64 { return s.Connects })
+	counter("lcn_mqtt_connections_lost_total", "Lost connections to the MQTT broker.",
+		func(s broker.Stats) uint64 { return s.ConnectionsLost })
+
+	registry.GaugeFunc("lcn_mqtt_buffered", "Publishes waiting for a connection.", func() float64 {
+		return float64(b.Stats().Buffered)
+	})
+}
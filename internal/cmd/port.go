@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	bugst "go.bug.st/serial"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+// NewCodec returns the codec validating received frames as configured in the lcn section.
+func NewCodec(cfg *config.LcnConfig) *lcn.Codec {
+	name := cfg.Checksum
+	if name == "" {
+		name = lcn.DefaultChecksum
+	}
+
+	checksum, err := lcn.ChecksumByName(name)
+	if err != nil {
+		log.Fatalf("Invalid lcn.checksum: %s", err)
+	}
+
+	if name != lcn.DefaultChecksum || cfg.AcceptInvalidChecksum {
+		log.Warnf("Validating frames with the %s checksum, accepting invalid frames: %t", name, cfg.AcceptInvalidChecksum)
+	}
+
+	return lcn.NewCodec(lcn.Checksum(checksum), lcn.AcceptInvalid(cfg.AcceptInvalidChecksum))
+}
+
+// NewPort opens the LCN bus described by cfg, or replays a capture if one is configured.
+func NewPort(cfg *config.Config, observers ...serial.Observer) serial.Port {
+	codec := NewCodec(&cfg.Lcn)
+
+	if cfg.Capture.Replay != "" {
+		log.Infof("Replaying %s instead of serial(%s)", cfg.Capture.Replay, cfg.Serial.Port)
+
+		replayOptions := []capture.Option{
+			capture.Deserializer(codec.Deserialize),
+			capture.Speed(cfg.Capture.Speed),
+		}
+
+		if cfg.Serial.MaxGap > 0 {
+			replayOptions = append(replayOptions, capture.MaxGap(cfg.Serial.MaxGap))
+		}
+
+		if cfg.Serial.MaxFrameAge > 0 {
+			replayOptions = append(replayOptions, capture.MaxFrameAge(cfg.Serial.MaxFrameAge))
+		}
+
+		for _, observer := range observers {
+			replayOptions = append(replayOptions, capture.Observe(observer))
+		}
+
+		return capture.NewReplay(cfg.Capture.Replay, replayOptions...)
+	}
+
+	// the port would retry an invalid address forever
+	if _, err := serial.NewTransport(cfg.Serial.Port, bugst.Mode{}); err != nil {
+		log.Fatalf("Invalid serial port: %s", err)
+	}
+
+	portOptions := []serial.Option{
+		serial.BaudRate(cfg.Serial.BaudRate),
+		serial.PortName(cfg.Serial.Port),
+		serial.Deserializer(codec.Deserialize),
+		serial.FailOnDisconnect(cfg.Serial.FailOnDisconnect),
+	}
+
+	if cfg.Serial.BusIdle > 0 {
+		portOptions = append(portOptions, serial.BusIdle(cfg.Serial.BusIdle))
+	}
+
+	if cfg.Serial.FrameSpacing > 0 {
+		portOptions = append(portOptions, serial.FrameSpacing(cfg.Serial.FrameSpacing))
+	}
+
+	if cfg.Serial.MaxRate > 0 {
+		portOptions = append(portOptions, serial.MaxRate(cfg.Serial.MaxRate))
+	}
+
+	if cfg.Serial.MaxGap > 0 {
+		portOptions = append(portOptions, serial.MaxGap(cfg.Serial.MaxGap))
+	}
+
+	if cfg.Serial.MaxFrameAge > 0 {
+		portOptions = append(portOptions, serial.MaxFrameAge(cfg.Serial.MaxFrameAge))
+	}
+
+	for _, observer := range observers {
+		portOptions = append(portOptions, serial.Observe(observer))
+	}
+
+	return serial.NewPort(portOptions...)
+}
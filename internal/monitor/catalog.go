@@ -0,0 +1,219 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+const (
+	OutputTypeRelay  = "relay"
+	OutputTypeDimmer = "dimmer"
+	OutputTypeShade  = "shade"
+
+	maxOutput = 8
+)
+
+var ErrInvalidDevice = errors.New("invalid device configuration")
+
+type moduleKey struct {
+	segment int
+	module  int
+}
+
+type moduleInfo struct {
+	name    string
+	outputs map[int]config.OutputConfig // keyed by zero based output
+}
+
+// Catalog holds the names and types of segments, modules and outputs of an installation.
+type Catalog struct {
+	modules map[moduleKey]*moduleInfo
+}
+
+// NewCatalog builds a catalog from the devices section of the config.
+func NewCatalog(cfg config.DevicesConfig) (*Catalog, error) {
+	c := &Catalog{
+		modules: make(map[moduleKey]*moduleInfo),
+	}
+
+	for _, segment := range cfg.Segments {
+		for _, module := range segment.Modules {
+			key := moduleKey{segment: segment.ID, module: module.ID}
+			if _, ok := c.modules[key]; ok {
+				return nil, errors.Wrapf(ErrInvalidDevice, "module %d in segment %d configured twice", module.ID, segment.ID)
+			}
+
+			info := &moduleInfo{
+				name:    module.Name,
+				outputs: make(map[int]config.OutputConfig),
+			}
+
+			for _, output := range module.Outputs {
+				if err := validateOutput(output); err != nil {
+					return nil, errors.Wrapf(err, "module %d in segment %d", module.ID, segment.ID)
+				}
+
+				info.outputs[output.ID-1] = output
+			}
+
+			if err := validateShades(info.outputs); err != nil {
+				return nil, errors.Wrapf(err, "module %d in segment %d", module.ID, segment.ID)
+			}
+
+			c.modules[key] = info
+		}
+	}
+
+	return c, nil
+}
+
+func validateOutput(output config.OutputConfig) error {
+	if output.ID < 1 || output.ID > maxOutput {
+		return errors.Wrapf(ErrInvalidDevice, "output %d out of range", output.ID)
+	}
+
+	switch output.Type {
+	case OutputTypeRelay, OutputTypeDimmer:
+	case OutputTypeShade:
+		if output.Down < 1 || output.Down > maxOutput || output.Down == output.ID {
+			return errors.Wrapf(ErrInvalidDevice, "shade %d needs a distinct down relais", output.ID)
+		}
+
+		if output.TravelUp < 0 || output.TravelDown < 0 || (output.TravelUp == 0) != (output.TravelDown == 0) {
+			return errors.Wrapf(ErrInvalidDevice, "shade %d needs both travel times or none", output.ID)
+		}
+	default:
+		return errors.Wrapf(ErrInvalidDevice, "output %d has unknown type %q", output.ID, output.Type)
+	}
+
+	return nil
+}
+
+// validateShades refuses down relais of shades that are configured as another output or shared by two shades.
+func validateShades(outputs map[int]config.OutputConfig) error {
+	down := make(map[int]int) // down relais to shade
+
+	for _, output := range outputs {
+		if output.Type != OutputTypeShade {
+			continue
+		}
+
+		if other, ok := outputs[output.Down-1]; ok {
+			return errors.Wrapf(ErrInvalidDevice, "down relais %d of shade %d is configured as output %d",
+				output.Down, output.ID, other.ID)
+		}
+
+		if other, ok := down[output.Down]; ok {
+			return errors.Wrapf(ErrInvalidDevice, "down relais %d is shared by shades %d and %d",
+				output.Down, min(other, output.ID), max(other, output.ID))
+		}
+
+		down[output.Down] = output.ID
+	}
+
+	return nil
+}
+
+// lookup finds a module by segment, falling back to the module with that ID in the lowest segment.
+func (c *Catalog) lookup(segment, module int) (moduleKey, *moduleInfo, bool) {
+	key := moduleKey{segment: segment, module: module}
+	if info, ok := c.modules[key]; ok {
+		return key, info, true
+	}
+
+	found := false
+	result := key
+
+	var resultInfo *moduleInfo
+
+	for k, info := range c.modules {
+		if k.module == module && (!found || k.segment < result.segment) {
+			found, result, resultInfo = true, k, info
+		}
+	}
+
+	return result, resultInfo, found
+}
+
+// ModuleName returns the name of a module, or its ID if unknown.
+func (c *Catalog) ModuleName(segment, module int) string {
+	if _, info, ok := c.lookup(segment, module); ok && info.name != "" {
+		return info.name
+	}
+
+	return fmt.Sprintf("%d", module)
+}
+
+// OutputName returns the name of a zero based output of a module, or module and output if unknown.
+func (c *Catalog) OutputName(segment, module, output int) string {
+	if _, info, ok := c.lookup(segment, module); ok {
+		if o, ok := info.outputs[output]; ok {
+			return o.Name
+		}
+	}
+
+	return fmt.Sprintf("%d-%d", module, output)
+}
+
+// Segment returns the segment of a module, the lowest one if its ID is configured in several segments,
+// 0 if unknown.
+func (c *Catalog) Segment(module int) int {
+	key, _, _ := c.lookup(0, module)
+
+	return key.segment
+}
+
+// Modules returns all configured modules, sorted by segment and module.
+func (c *Catalog) Modules() []Module {
+	modules := make([]Module, 0, len(c.modules))
+
+	for key, info := range c.modules {
+		modules = append(modules, Module{Segment: key.segment, Module: key.module, Name: info.name})
+	}
+
+	slices.SortFunc(modules, func(a, b Module) int {
+		return Device{Segment: a.Segment, Module: a.Module}.compare(Device{Segment: b.Segment, Module: b.Module})
+	})
+
+	return modules
+}
+
+// Devices returns all configured outputs as devices, sorted by segment, module and output.
+func (c *Catalog) Devices() Devices {
+	var devices Devices
+
+	for key, info := range c.modules {
+		for output, cfg := range info.outputs {
+			device := Device{Segment: key.segment, Module: key.module, Output: output, Name: cfg.Name}
+
+			switch cfg.Type {
+			case OutputTypeRelay:
+				devices.Lights = append(devices.Lights, Light{Device: device})
+			case OutputTypeDimmer:
+				devices.Dimmers = append(devices.Dimmers, DimabableLight{Device: device})
+			case OutputTypeShade:
+				devices.Shades = append(devices.Shades, Shade{
+					Device:     device,
+					Down:       cfg.Down - 1,
+					TravelUp:   cfg.TravelUp,
+					TravelDown: cfg.TravelDown,
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(devices.Lights, func(a, b Light) int { return a.compare(b.Device) })
+	slices.SortFunc(devices.Shades, func(a, b Shade) int { return a.compare(b.Device) })
+	slices.SortFunc(devices.Dimmers, func(a, b DimabableLight) int { return a.compare(b.Device) })
+
+	return devices
+}
+
+func mapCommand(cmd int) string {
+	return command.Name(byte(cmd))
+}
@@ -0,0 +1,78 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+)
+
+func TestCatalogValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		outputs []config.OutputConfig
+		valid   bool
+	}{
+		{
+			name:    "shade",
+			outputs: []config.OutputConfig{{ID: 1, Down: 2, Type: "shade"}, {ID: 3, Type: "relay"}},
+			valid:   true,
+		},
+		{
+			name:    "down relais configured as output",
+			outputs: []config.OutputConfig{{ID: 1, Down: 2, Type: "shade"}, {ID: 2, Type: "relay"}},
+		},
+		{
+			name:    "down relais of one shade is the up relais of another",
+			outputs: []config.OutputConfig{{ID: 1, Down: 2, Type: "shade"}, {ID: 2, Down: 3, Type: "shade"}},
+		},
+		{
+			name:    "down relais shared",
+			outputs: []config.OutputConfig{{ID: 1, Down: 3, Type: "shade"}, {ID: 2, Down: 3, Type: "shade"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := monitor.NewCatalog(config.DevicesConfig{
+				Segments: []config.SegmentConfig{{Modules: []config.ModuleConfig{{ID: 31, Outputs: tt.outputs}}}},
+			})
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, monitor.ErrInvalidDevice)
+			}
+		})
+	}
+}
+
+func TestCatalogLookup(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{
+			{ID: 7, Modules: []config.ModuleConfig{{ID: 12, Name: "seven"}}},
+			{ID: 5, Modules: []config.ModuleConfig{{ID: 12, Name: "five"}}},
+			{ID: 9, Modules: []config.ModuleConfig{{ID: 12, Name: "nine"}, {ID: 13, Name: "thirteen"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	// a module ID configured in several segments resolves to the lowest one unless the segment matches
+	for range 20 {
+		assert.Equal(t, 5, catalog.Segment(12))
+		assert.Equal(t, "five", catalog.ModuleName(0, 12))
+	}
+
+	assert.Equal(t, "nine", catalog.ModuleName(9, 12))
+	assert.Equal(t, 9, catalog.Segment(13))
+	assert.Equal(t, 0, catalog.Segment(14))
+}
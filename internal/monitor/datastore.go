@@ -12,14 +12,32 @@ import (
 )
 
 type DataStore struct {
-	messages map[string]*message
-	mutex    sync.Mutex
+	catalog   *Catalog
+	messages  map[string]*message
+	histories map[Stream]*history
+	events    []Event
+	marks     int
+	mutex     sync.Mutex
 }
 
 type message struct {
 	lcn.LcnPacket
 	lastSeen time.Time
 	times    int
+	changed  []bool // payload bytes differing from the previous frame of the stream
+}
+
+// Entry is a distinct frame seen by the DataStore, rendered with the names of the catalog.
+type Entry struct {
+	Packet   lcn.LcnPacket
+	LastSeen time.Time
+	Count    int
+	Changed  []bool // payload bytes differing from the previous frame of the same source, target and command
+
+	Src     string
+	Dst     string
+	Command string
+	Payload string // decoded if possible
 }
 
 func (d *DataStore) Add(pkt lcn.LcnPacket) {
@@ -28,39 +46,134 @@ func (d *DataStore) Add(pkt lcn.LcnPacket) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	{
+		sample := Sample{
+			Time:    now,
+			Payload: append([]byte{}, pkt.Payload...),
+			Event:   d.eventBefore(now),
+		}
+
+		s := StreamOf(&pkt)
+
+		h, seen := d.histories[s]
+		if seen {
+			sample.Flipped = flips(h.samples[len(h.samples)-1].Payload, sample.Payload)
+		} else {
+			h = &history{}
+			d.histories[s] = h
+		}
+
+		h.add(sample)
+
+		changed := changedBytes(sample.Flipped)
 
 		key := pkt.ToString()
 		if v, ok := d.messages[key]; ok {
 			v.Update(now)
+			v.changed = changed
 
-			log.Infof("UPD: %s", v.ToString())
+			log.Infof("UPD: %s%s", d.format(v), renderSample(&sample))
 		} else {
 			m := message{
 				LcnPacket: pkt,
 				lastSeen:  now,
 				times:     1,
+				changed:   changed,
 			}
 			d.messages[key] = &m
 
-			log.Infof("ADD: %s", m.ToString())
+			log.Infof("ADD: %s%s", d.format(&m), renderSample(&sample))
 		}
 	}
 }
 
-func (d *DataStore) GetLast(n int) []message {
+// Entries returns all distinct frames, the most recent first.
+func (d *DataStore) Entries() []Entry {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	result := make([]message, 0, len(d.messages))
-	for _, v := range d.messages {
-		result = append(result, *v)
+	entries := make([]Entry, 0, len(d.messages))
+
+	for _, m := range d.messages {
+		entries = append(entries, Entry{
+			Packet:   m.LcnPacket,
+			LastSeen: m.lastSeen,
+			Count:    m.times,
+			Changed:  m.changed,
+			Src:      d.catalog.ModuleName(int(m.Seg), int(m.Src)),
+			Dst:      d.catalog.ModuleName(int(m.Seg), int(m.Dst)),
+			Command:  mapCommand(int(m.Cmd)),
+			Payload:  d.catalog.renderPayload(&m.LcnPacket),
+		})
 	}
 
-	slices.SortFunc(result, func(a, b message) int {
-		return -a.lastSeen.Compare(b.lastSeen)
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return -a.LastSeen.Compare(b.LastSeen)
 	})
 
-	return result[:n]
+	return entries
+}
+
+// Diff returns the changes of the payload of a stream.
+func (d *DataStore) Diff(s Stream) (Diff, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	h, ok := d.histories[s]
+	if !ok {
+		return Diff{}, false
+	}
+
+	return h.diff(s), true
+}
+
+// Mark records a wall-clock event, frames following within CorrelationWindow are attributed to it.
+// Without a name events are numbered.
+func (d *DataStore) Mark(name string) Event {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.marks++
+
+	if name == "" {
+		name = fmt.Sprintf("mark %d", d.marks)
+	}
+
+	event := Event{Time: time.Now(), Name: name}
+
+	if len(d.events) == maxEvents {
+		d.events = append(d.events[:0], d.events[1:]...)
+	}
+
+	d.events = append(d.events, event)
+
+	log.Infof("MARK: %s", name)
+
+	return event
+}
+
+// Events returns the marked events, oldest first.
+func (d *DataStore) Events() []Event {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]Event{}, d.events...)
+}
+
+func (d *DataStore) eventBefore(t time.Time) *Event {
+	for i := len(d.events) - 1; i >= 0; i-- {
+		e := d.events[i]
+		if e.Time.After(t) {
+			continue
+		}
+
+		if t.Sub(e.Time) > CorrelationWindow {
+			return nil
+		}
+
+		return &e
+	}
+
+	return nil
 }
 
 func (m *message) Update(lastSeen time.Time) {
@@ -68,23 +181,14 @@ func (m *message) Update(lastSeen time.Time) {
 	m.times++
 }
 
-func (m *message) ToString() string {
-	const lineLength = 7
-
-	line := make([]string, 0, lineLength)
-	line = append(line, m.lastSeen.Local().Format("2006-01-02 15:04:05 MST"))
-	line = append(line, fmt.Sprintf("%d", m.times))
-	line = append(line, mapIfPossible(idMap, int(m.Src)))
-	line = append(line, fmt.Sprintf("%d", m.Seg))
-	line = append(line, mapIfPossible(idMap, int(m.Dst)))
-	line = append(line, mapIfPossible(cmdMap, int(m.Cmd)))
-	line = append(line, parsePayloadIfPossible(int(m.Src), int(m.Dst), int(m.Cmd), m.Payload))
-
-	return strings.Join(line, "\t")
+func (d *DataStore) format(m *message) string {
+	return strings.Join(d.catalog.renderMessage(m), "\t")
 }
 
-func NewDataStore() *DataStore {
+func NewDataStore(catalog *Catalog) *DataStore {
 	return &DataStore{
-		messages: make(map[string]*message),
+		catalog:   catalog,
+		messages:  make(map[string]*message),
+		histories: make(map[Stream]*history),
 	}
 }
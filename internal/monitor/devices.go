@@ -1,28 +1,60 @@
 package monitor
 
-type ShadeState int
+import "time"
 
-const (
-	ShadeStateStopped ShadeState = iota
-	ShadeStateUp
-	ShadeStateDown
-)
+// Module identifies a configured module.
+type Module struct {
+	Segment int
+	Module  int
+	Name    string
+}
 
+// Device identifies a named output of a module, outputs are zero based relais indices.
 type Device struct {
-	name string
+	Segment int
+	Module  int
+	Output  int
+	Name    string
 }
 
+// Light is switched by a relais, its state is kept by the state registry.
 type Light struct {
 	Device
-	status bool
 }
 
+// Shade is driven by a pair of relais, Output moves it up and Down moves it down.
 type Shade struct {
 	Device
-	status ShadeState
+	Down int
+
+	// time to open or close fully, zero if unknown
+	TravelUp   time.Duration
+	TravelDown time.Duration
+}
+
+// Travels reports whether the travel times are known, so positions can be estimated.
+func (s Shade) Travels() bool {
+	return s.TravelUp > 0 && s.TravelDown > 0
 }
 
 type DimabableLight struct {
 	Device
-	dim byte // 0-255
+}
+
+type Devices struct {
+	Lights  []Light
+	Shades  []Shade
+	Dimmers []DimabableLight
+}
+
+func (d Device) compare(o Device) int {
+	if d.Segment != o.Segment {
+		return d.Segment - o.Segment
+	}
+
+	if d.Module != o.Module {
+		return d.Module - o.Module
+	}
+
+	return d.Output - o.Output
 }
@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+const (
+	historyLength = 64  // samples kept per stream
+	maxEvents     = 256 // marked events kept
+
+	// CorrelationWindow is how long after an event a change is attributed to it.
+	CorrelationWindow = 3 * time.Second
+)
+
+// Stream are the frames of one command between the same modules, consecutive payloads of a
+// stream are compared to find the bits carrying information.
+type Stream struct {
+	Src, Seg, Dst, Cmd byte
+}
+
+func StreamOf(pkt *lcn.LcnPacket) Stream {
+	return Stream{Src: pkt.Src, Seg: pkt.Seg, Dst: pkt.Dst, Cmd: pkt.Cmd}
+}
+
+func (s Stream) String() string {
+	return fmt.Sprintf("%d -> %d:%d cmd %02x", s.Src, s.Seg, s.Dst, s.Cmd)
+}
+
+// Event is a wall-clock event marked while watching the bus, e.g. a switch being pressed.
+type Event struct {
+	Time time.Time
+	Name string
+}
+
+// Sample is a frame of a stream.
+type Sample struct {
+	Time    time.Time
+	Payload []byte
+	Flipped []byte // bits differing from the previous payload, nil for the first frame
+	Event   *Event // latest event at most CorrelationWindow before the frame
+}
+
+// Changed reports whether the payload differs from the previous frame of the stream.
+func (s *Sample) Changed() bool {
+	for _, b := range s.Flipped {
+		if b != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Diff reports how often each payload byte and bit of a stream changed.
+type Diff struct {
+	Stream      Stream
+	Frames      int      // frames seen
+	Changes     int      // frames differing from their predecessor
+	ByteChanges []int    // per payload byte
+	BitChanges  [][8]int // per payload byte and bit, bit 0 is the least significant
+	Samples     []Sample // the most recent frames, oldest first
+}
+
+type history struct {
+	frames      int
+	changes     int
+	byteChanges []int
+	bitChanges  [][8]int
+	samples     []Sample
+}
+
+func (h *history) add(sample Sample) {
+	h.frames++
+
+	if sample.Changed() {
+		h.changes++
+	}
+
+	for i, flipped := range sample.Flipped {
+		if i >= len(h.byteChanges) {
+			h.byteChanges = append(h.byteChanges, 0)
+			h.bitChanges = append(h.bitChanges, [8]int{})
+		}
+
+		if flipped == 0 {
+			continue
+		}
+
+		h.byteChanges[i]++
+
+		for bit := 0; bit < 8; bit++ {
+			if flipped&(1<<bit) != 0 {
+				h.bitChanges[i][bit]++
+			}
+		}
+	}
+
+	if len(h.samples) == historyLength {
+		h.samples = append(h.samples[:0], h.samples[1:]...)
+	}
+
+	h.samples = append(h.samples, sample)
+}
+
+func (h *history) diff(s Stream) Diff {
+	return Diff{
+		Stream:      s,
+		Frames:      h.frames,
+		Changes:     h.changes,
+		ByteChanges: append([]int{}, h.byteChanges...),
+		BitChanges:  append([][8]int{}, h.bitChanges...),
+		Samples:     append([]Sample{}, h.samples...),
+	}
+}
+
+// flips returns the bits of payload differing from previous, bytes missing in previous count as all flipped.
+func flips(previous, payload []byte) []byte {
+	flipped := make([]byte, len(payload))
+
+	for i := range payload {
+		if i < len(previous) {
+			flipped[i] = previous[i] ^ payload[i]
+		} else {
+			flipped[i] = 0xff
+		}
+	}
+
+	return flipped
+}
+
+// changedBytes marks the bytes with flipped bits.
+func changedBytes(flipped []byte) []bool {
+	if flipped == nil {
+		return nil
+	}
+
+	changed := make([]bool, len(flipped))
+	for i, b := range flipped {
+		changed[i] = b != 0
+	}
+
+	return changed
+}
@@ -0,0 +1,68 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{})
+	require.NoError(t, err)
+
+	d := monitor.NewDataStore(catalog)
+
+	frame := func(payload ...byte) lcn.LcnPacket {
+		return lcn.LcnPacket{Src: 11, Seg: 0, Dst: 4, Cmd: 0x22, Payload: payload}
+	}
+
+	d.Add(frame(0x01, 0x10))
+	d.Add(frame(0x01, 0x10))
+	event := d.Mark("")
+	d.Add(frame(0x01, 0x13))
+	d.Add(frame(0x81, 0x12, 0xff))
+	d.Add(lcn.LcnPacket{Src: 11, Seg: 0, Dst: 5, Cmd: 0x22, Payload: []byte{0x00}})
+
+	diff, ok := d.Diff(monitor.Stream{Src: 11, Seg: 0, Dst: 4, Cmd: 0x22})
+	require.True(t, ok)
+
+	assert.Equal(t, 4, diff.Frames)
+	assert.Equal(t, 2, diff.Changes)
+	assert.Equal(t, []int{1, 2, 1}, diff.ByteChanges)
+	assert.Equal(t, [][8]int{
+		{7: 1},
+		{0: 2, 1: 1},
+		{1, 1, 1, 1, 1, 1, 1, 1},
+	}, diff.BitChanges)
+
+	require.Len(t, diff.Samples, 4)
+	assert.Nil(t, diff.Samples[0].Flipped)
+	assert.False(t, diff.Samples[1].Changed())
+	assert.Nil(t, diff.Samples[1].Event)
+	assert.Equal(t, []byte{0x00, 0x03}, diff.Samples[2].Flipped)
+	assert.Equal(t, &event, diff.Samples[2].Event)
+	assert.Equal(t, "mark 1", event.Name)
+
+	// a new payload variant is a new entry, still compared within its stream
+	changed := make(map[string][]bool)
+	for _, e := range d.Entries() {
+		changed[e.Payload] = e.Changed
+	}
+
+	assert.Equal(t, map[string][]bool{
+		"0110":   {false, false},
+		"0113":   {false, true},
+		"8112ff": {true, true, true},
+		"00":     nil,
+	}, changed)
+
+	_, ok = d.Diff(monitor.Stream{Src: 1})
+	assert.False(t, ok)
+}
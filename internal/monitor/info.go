@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+// InfoTally counts the INFO bytes seen per command and per source, to find out what the bits
+// besides the length depend on.
+type InfoTally struct {
+	catalog   *Catalog
+	byCommand map[string]map[byte]int
+	bySource  map[string]map[byte]int
+	mutex     sync.Mutex
+}
+
+func NewInfoTally(catalog *Catalog) *InfoTally {
+	return &InfoTally{
+		catalog:   catalog,
+		byCommand: make(map[string]map[byte]int),
+		bySource:  make(map[string]map[byte]int),
+	}
+}
+
+func (t *InfoTally) Add(pkt *lcn.LcnPacket) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	count(t.byCommand, mapCommand(int(pkt.Cmd)), pkt.Info)
+	count(t.bySource, fmt.Sprintf("%d:%s", pkt.Seg, t.catalog.ModuleName(int(pkt.Seg), int(pkt.Src))), pkt.Info)
+}
+
+func count(groups map[string]map[byte]int, group string, info byte) {
+	counts, ok := groups[group]
+	if !ok {
+		counts = make(map[byte]int)
+		groups[group] = counts
+	}
+
+	counts[info]++
+}
+
+// Report writes how often each guessed INFO flag was set and the INFO bytes seen,
+// per command and per source.
+func (t *InfoTally) Report(w io.Writer) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd
+
+	writeInfoTable(table, "command", t.byCommand)
+	fmt.Fprintln(table)
+	writeInfoTable(table, "source", t.bySource)
+
+	return errors.Wrap(table.Flush(), "cannot write report")
+}
+
+func writeInfoTable(w io.Writer, name string, groups map[string]map[byte]int) {
+	fmt.Fprintf(w, "%s\tframes\tack?\tfrom module?\tpriority?\trouted?\tINFO bytes\n", name)
+
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+
+	sort.Strings(names)
+
+	for _, group := range names {
+		var frames, ack, fromModule, priority, routed int
+
+		infos := make([]int, 0, len(groups[group]))
+
+		for info, n := range groups[group] {
+			fields := lcn.DecodeInfo(info)
+			frames += n
+
+			if fields.AckRequested {
+				ack += n
+			}
+
+			if fields.FromModule {
+				fromModule += n
+			}
+
+			if fields.Priority != 0 {
+				priority += n
+			}
+
+			if fields.Routed != 0 {
+				routed += n
+			}
+
+			infos = append(infos, int(info))
+		}
+
+		sort.Ints(infos)
+
+		patterns := make([]string, len(infos))
+		for i, info := range infos {
+			patterns[i] = fmt.Sprintf("%08b×%d", info, groups[group][byte(info)])
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			group, frames, ack, fromModule, priority, routed, strings.Join(patterns, " "))
+	}
+}
@@ -0,0 +1,41 @@
+package monitor_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+func TestInfoTally(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{Segments: []config.SegmentConfig{{
+		ID:      0,
+		Modules: []config.ModuleConfig{{ID: 21, Name: "Hallway"}},
+	}}})
+	require.NoError(t, err)
+
+	tally := monitor.NewInfoTally(catalog)
+	tally.Add(&lcn.LcnPacket{Src: 21, Info: 0x06, Cmd: 0x68})
+	tally.Add(&lcn.LcnPacket{Src: 21, Info: 0x06, Cmd: 0x68})
+	tally.Add(&lcn.LcnPacket{Src: 1, Info: 0x04, Cmd: 0x13})
+	tally.Add(&lcn.LcnPacket{Src: 31, Seg: 4, Info: 0x4e, Cmd: 0x22})
+
+	var report strings.Builder
+	require.NoError(t, tally.Report(&report))
+
+	lines := strings.Split(report.String(), "\n")
+	require.GreaterOrEqual(t, len(lines), 8)
+
+	assert.Equal(t, []string{"command", "frames", "ack?", "from", "module?", "priority?", "routed?", "INFO", "bytes"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{"0x22", "1", "0", "1", "0", "1", "01001110×1"}, strings.Fields(lines[1]))
+	assert.Contains(t, report.String(), "0:Hallway")
+	assert.Regexp(t, `0:Hallway\s+2\s+0\s+2\s+0\s+0\s+00000110×2`, report.String())
+}
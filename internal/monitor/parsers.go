@@ -4,86 +4,90 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
 )
 
 func defaultPayloadParser(_, _ int, payload []byte) string {
 	return hex.EncodeToString(payload)
 }
 
-func parsePayloadIfPossible(src, dst, cmd int, payload []byte) string {
-	if f, ok := payloadParserByCommand[cmd]; ok {
-		return f(src, dst, payload)
+// renderPayload decodes the payload of a frame, frames with an invalid checksum are not decoded.
+func (c *Catalog) renderPayload(pkt *lcn.LcnPacket) string {
+	if pkt.Invalid {
+		return "invalid checksum " + hex.EncodeToString(pkt.Payload)
 	}
 
-	return defaultPayloadParser(src, dst, payload)
-}
-
-func testDigit(b byte, out int) bool {
-	return b&(1<<uint(out)) != 0
+	return c.parsePayloadIfPossible(int(pkt.Seg), int(pkt.Src), int(pkt.Dst), int(pkt.Cmd), pkt.Payload)
 }
 
-func decodeRelais(_, dst int, payload []byte) string {
-	outputs := make([]string, 0)
-
-	for i := 0; i < 8; i++ {
-		bitPositions := payload[0] | payload[1]
-
-		if testDigit(bitPositions, i) {
-			outputName := mapOutputIfPossible(dst, i)
-			force := testDigit(payload[0], i)
-			toggle := testDigit(payload[1], i)
-
-			switch {
-			case force && toggle:
-				outputs = append(outputs, fmt.Sprintf("<%s: FORCE OFF>", outputName))
-			case force && !toggle:
-				outputs = append(outputs, fmt.Sprintf("<%s: FORCE ON>", outputName))
-			case !force:
-				outputs = append(outputs, fmt.Sprintf("<%s: TOGGLE>", outputName))
-			}
-		}
+func (c *Catalog) parsePayloadIfPossible(seg, src, dst, cmd int, payload []byte) string {
+	decoded, err := command.DecodePayload(byte(cmd), payload)
+	if err != nil {
+		return defaultPayloadParser(src, dst, payload)
 	}
 
-	return strings.Join(outputs, ",")
-}
+	switch d := decoded.(type) {
+	case *command.RelaisCommand:
+		return c.decodeRelais(seg, dst, d)
+	case *command.RelaisStatus:
+		if dst != command.StatusReportTarget {
+			return defaultPayloadParser(src, dst, payload)
+		}
 
-func decodeStatusReport(src, dst int, payload []byte) string {
-	if payload[0] != 0x30 || dst != 4 {
+		return c.decodeStatusReport(seg, src, d)
+	case *command.StatusQuery:
+		return c.decodeStatusQuery(seg, src, dst, d)
+	default:
 		return defaultPayloadParser(src, dst, payload)
 	}
+}
 
+func (c *Catalog) decodeRelais(seg, dst int, relais *command.RelaisCommand) string {
 	outputs := make([]string, 0)
 
-	for i := 0; i < 8; i++ {
-		if testDigit(payload[1], i) {
-			outputs = append(outputs, mapOutputIfPossible(src, i))
+	for i, action := range relais.Outputs {
+		outputName := c.OutputName(seg, dst, i)
+
+		switch action {
+		case command.RelaisOff:
+			outputs = append(outputs, fmt.Sprintf("<%s: FORCE OFF>", outputName))
+		case command.RelaisOn:
+			outputs = append(outputs, fmt.Sprintf("<%s: FORCE ON>", outputName))
+		case command.RelaisToggle:
+			outputs = append(outputs, fmt.Sprintf("<%s: TOGGLE>", outputName))
+		case command.RelaisNoChange:
 		}
 	}
 
 	return strings.Join(outputs, ",")
 }
 
-func decodeStatusQuery(src, dst int, payload []byte) string {
-	if payload[0] != 0xFB && payload[0] != 0x7b {
-		return defaultPayloadParser(src, dst, payload)
-	}
+func (c *Catalog) decodeStatusReport(seg, src int, status *command.RelaisStatus) string {
+	return strings.Join(c.mapOutputs(seg, src, status.Outputs), ",")
+}
 
-	var operation string
-	module := src
-	if payload[0] == 0xFB {
-		operation = "QUERY: "
-		module = dst
-	} else if payload[0] == 0x7b {
+func (c *Catalog) decodeStatusQuery(seg, src, dst int, query *command.StatusQuery) string {
+	operation := "QUERY: "
+	module := dst
+
+	if query.Response {
 		operation = "REPORT: "
+		module = src
 	}
 
-	outputs := make([]string, 0)
+	return operation + strings.Join(c.mapOutputs(seg, module, query.Outputs), ",")
+}
+
+func (c *Catalog) mapOutputs(seg, module int, outputs [8]bool) []string {
+	names := make([]string, 0)
 
-	for i := 0; i < 8; i++ {
-		if testDigit(payload[1], i) {
-			outputs = append(outputs, mapOutputIfPossible(module, i))
+	for i, on := range outputs {
+		if on {
+			names = append(names, c.OutputName(seg, module, i))
 		}
 	}
 
-	return operation + strings.Join(outputs, ",")
+	return names
 }
@@ -0,0 +1,34 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+func TestStatusReportTarget(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{{
+			Modules: []config.ModuleConfig{{ID: 33, Outputs: []config.OutputConfig{{ID: 2, Type: "relay", Name: "Light"}}}},
+		}},
+	})
+	require.NoError(t, err)
+
+	d := monitor.NewDataStore(catalog)
+	d.Add(lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x02}})
+	d.Add(lcn.LcnPacket{Src: 33, Dst: 5, Cmd: 0x68, Payload: []byte{0x30, 0x02}})
+
+	payloads := make(map[byte]string)
+	for _, entry := range d.Entries() {
+		payloads[entry.Packet.Dst] = entry.Payload
+	}
+
+	assert.Equal(t, map[byte]string{4: "Light", 5: "3002"}, payloads)
+}
@@ -2,41 +2,43 @@ package monitor
 
 import (
 	"fmt"
-	"slices"
+	"strings"
+	"time"
 )
 
-//nolint:deadcode,unused
-func renderData(data map[string]*message) [][]string {
-	dataSlice := make([]*message, 0, len(data))
+func (c *Catalog) renderMessage(m *message) []string {
+	const lineLength = 7
 
-	for _, v := range data {
-		dataSlice = append(dataSlice, v)
-	}
-
-	slices.SortFunc(dataSlice, func(a, b *message) int {
-		return -a.lastSeen.Compare(b.lastSeen)
-	})
+	line := make([]string, 0, lineLength)
+	line = append(line, m.lastSeen.Local().Format("2006-01-02 15:04:05 MST"))
+	line = append(line, fmt.Sprintf("%d", m.times))
+	line = append(line, c.ModuleName(int(m.Seg), int(m.Src)))
+	line = append(line, fmt.Sprintf("%d", m.Seg))
+	line = append(line, c.ModuleName(int(m.Seg), int(m.Dst)))
+	line = append(line, mapCommand(int(m.Cmd)))
+	line = append(line, c.renderPayload(&m.LcnPacket))
 
-	lines := make([][]string, 0, len(dataSlice))
+	return line
+}
 
-	for _, v := range dataSlice {
-		const lineLength = 7
+// renderSample lists the flipped payload bits of a frame and the event it follows.
+func renderSample(s *Sample) string {
+	if !s.Changed() {
+		return ""
+	}
 
-		line := make([]string, 0, lineLength)
-		line = append(line, v.lastSeen.Local().Format("2006-01-02 15:04:05 MST"))
-		line = append(line, fmt.Sprintf("%d", v.times))
-		line = append(line, mapIfPossible(idMap, int(v.Src)))
-		line = append(line, fmt.Sprintf("%d", v.Seg))
-		line = append(line, mapIfPossible(idMap, int(v.Dst)))
-		line = append(line, mapIfPossible(cmdMap, int(v.Cmd)))
-		line = append(line, parsePayloadIfPossible(int(v.Src), int(v.Dst), int(v.Cmd), v.Payload))
+	flipped := make([]string, 0, len(s.Flipped))
 
-		lines = append(lines, line)
+	for i, b := range s.Flipped {
+		if b != 0 {
+			flipped = append(flipped, fmt.Sprintf("P%d %08b", i, b))
+		}
 	}
 
-	result := make([][]string, 0, len(lines)+1)
-	result = append(result, []string{"last seen", "#", "Src", "Seg", "Dst", "Command", "Payload"})
-	result = append(result, lines...)
+	line := "\tflipped: " + strings.Join(flipped, ", ")
+	if s.Event != nil {
+		line += fmt.Sprintf(" %s after %s", s.Time.Sub(s.Event.Time).Round(time.Millisecond), s.Event.Name)
+	}
 
-	return result
+	return line
 }
@@ -0,0 +1,42 @@
+package poller
+
+import (
+	"time"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		source   byte
+		interval time.Duration
+		spacing  time.Duration
+	}
+)
+
+// Source sets the module ID the status queries are sent from.
+func Source(source byte) Option {
+	return func(c *Config) {
+		c.source = source
+	}
+}
+
+// Interval sets how often all modules are polled, zero polls only once connected.
+func Interval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.interval = interval
+	}
+}
+
+// Spacing sets the pause between the queries of two modules, so the replies don't flood the bus.
+func Spacing(spacing time.Duration) Option {
+	return func(c *Config) {
+		c.spacing = spacing
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		source:  1,
+		spacing: 250 * time.Millisecond, //nolint:gomnd
+	}
+}
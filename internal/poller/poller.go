@@ -0,0 +1,116 @@
+// Package poller queries the status of all configured modules, so their state is known without waiting
+// for them to report a change.
+//
+// The replies are not awaited here, they are received like any other frame and reach the state
+// through the bridge.
+package poller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+// Poller sends a status query to every configured module once the port is connected and on an interval.
+type Poller struct {
+	port    serial.Port
+	modules []monitor.Module
+	config  *Config
+	trigger chan struct{}
+}
+
+// New creates a poller for the modules of the catalog.
+func New(port serial.Port, catalog *monitor.Catalog, options ...Option) *Poller {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return &Poller{
+		port:    port,
+		modules: catalog.Modules(),
+		config:  config,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Run polls whenever the port (re)connects and on the interval until ctx is done.
+func (p *Poller) Run(ctx context.Context) {
+	p.port.OnStateChange(func(state serial.State) {
+		if state == serial.StateConnected {
+			p.Trigger()
+		}
+	})
+
+	go p.loop(ctx)
+}
+
+// Trigger requests a poll, a poll already requested is not repeated.
+func (p *Poller) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Poller) loop(ctx context.Context) {
+	var tick <-chan time.Time
+
+	if p.config.interval > 0 {
+		ticker := time.NewTicker(p.config.interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.trigger:
+		case <-tick:
+		}
+
+		if err := p.Poll(ctx); err != nil {
+			log.Warnf("Polling modules: %s", err)
+		}
+	}
+}
+
+// Poll sends a status query to every module, spaced to spread the replies.
+func (p *Poller) Poll(ctx context.Context) error {
+	log.Debugf("Polling %d modules", len(p.modules))
+
+	for i, module := range p.modules {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Wrap(ctx.Err(), "polling cancelled")
+			case <-time.After(p.config.spacing):
+			}
+		}
+
+		pkt, err := command.NewPacket(p.config.source, byte(module.Segment), byte(module.Module), command.QueryAllRelais())
+		if err != nil {
+			return errors.Wrapf(err, "status query of module %d", module.Module)
+		}
+
+		buf, err := pkt.Serialize()
+		if err != nil {
+			return errors.Wrapf(err, "status query of module %d", module.Module)
+		}
+
+		if err := p.port.Send(ctx, buf); err != nil {
+			return errors.Wrapf(err, "status query of module %d", module.Module)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package poller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/poller"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+type fakePort struct {
+	sent     chan []byte
+	callback serial.StateCallback
+}
+
+func (p *fakePort) Run(context.Context, context.CancelFunc, chunker.EjectFunc) {}
+
+func (p *fakePort) Send(_ context.Context, buf []byte) error {
+	p.sent <- buf
+
+	return nil
+}
+
+func (p *fakePort) Request(ctx context.Context, buf []byte, _ serial.Matcher) (packet.Packet, error) {
+	return nil, p.Send(ctx, buf)
+}
+
+func (p *fakePort) QueueDepth() int {
+	return len(p.sent)
+}
+
+func (p *fakePort) State() serial.State {
+	return serial.StateDisconnected
+}
+
+func (p *fakePort) OnStateChange(callback serial.StateCallback) {
+	p.callback = callback
+	callback(serial.StateDisconnected)
+}
+
+func (p *fakePort) Stats() serial.Stats {
+	return serial.Stats{}
+}
+
+func newPoller(t *testing.T) (*poller.Poller, *fakePort) {
+	t.Helper()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{
+			{ID: 0, Modules: []config.ModuleConfig{{ID: 33}, {ID: 4, Name: "Display"}}},
+			{ID: 5, Modules: []config.ModuleConfig{{ID: 12}}},
+		},
+	})
+	require.NoError(t, err)
+
+	port := &fakePort{sent: make(chan []byte, 10)}
+
+	return poller.New(port, catalog, poller.Source(2), poller.Spacing(time.Millisecond)), port
+}
+
+func receive(t *testing.T, port *fakePort) *lcn.LcnPacket {
+	t.Helper()
+
+	select {
+	case buf := <-port.sent:
+		p, err := lcn.Deserialize(buf)
+		require.NoError(t, err)
+
+		pkt, ok := p.(*lcn.LcnPacket)
+		require.True(t, ok)
+
+		return pkt
+	case <-time.After(time.Second):
+		require.FailNow(t, "no status query sent")
+
+		return nil
+	}
+}
+
+func TestPoll(t *testing.T) {
+	t.Parallel()
+
+	p, port := newPoller(t)
+
+	require.NoError(t, p.Poll(context.Background()))
+
+	for _, expected := range [][2]byte{{0, 4}, {0, 33}, {5, 12}} {
+		pkt := receive(t, port)
+
+		assert.Equal(t, byte(2), pkt.Src)
+		assert.Equal(t, expected[0], pkt.Seg)
+		assert.Equal(t, expected[1], pkt.Dst)
+
+		cmd, err := command.Decode(pkt)
+		require.NoError(t, err)
+		assert.Equal(t, command.QueryAllRelais(), cmd)
+		assert.Equal(t, []byte{0xFB, 0xFF}, pkt.Payload)
+	}
+
+	assert.Empty(t, port.sent)
+}
+
+func TestRunPollsOnConnect(t *testing.T) {
+	t.Parallel()
+
+	p, port := newPoller(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p.Run(ctx)
+	assert.Empty(t, port.sent, "nothing is sent while disconnected")
+
+	port.callback(serial.StateConnected)
+
+	for range 3 {
+		receive(t, port)
+	}
+}
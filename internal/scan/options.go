@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		source   byte
+		catalog  *monitor.Catalog
+		segments []int
+		spacing  time.Duration
+		wait     time.Duration
+		observe  time.Duration
+	}
+)
+
+// Source sets the module ID the probes are sent from, frames of it are not taken for a module.
+func Source(source byte) Option {
+	return func(c *Config) {
+		c.source = source
+	}
+}
+
+// Catalog names the configured modules and places modules that did not answer in their configured segment.
+// Unless Segments is given, the configured segments are probed.
+func Catalog(catalog *monitor.Catalog) Option {
+	return func(c *Config) {
+		c.catalog = catalog
+	}
+}
+
+// Segments sets the segments to probe.
+func Segments(segments ...int) Option {
+	return func(c *Config) {
+		c.segments = segments
+	}
+}
+
+// Spacing sets the pause between two probes.
+func Spacing(spacing time.Duration) Option {
+	return func(c *Config) {
+		c.spacing = spacing
+	}
+}
+
+// Wait sets how long replies are awaited after the last probe of a segment.
+func Wait(wait time.Duration) Option {
+	return func(c *Config) {
+		c.wait = wait
+	}
+}
+
+// Observe sets how long the traffic is observed after probing.
+func Observe(observe time.Duration) Option {
+	return func(c *Config) {
+		c.observe = observe
+	}
+}
+
+func newDefaultConfig() *Config {
+	catalog, _ := monitor.NewCatalog(config.DevicesConfig{})
+
+	return &Config{
+		source:  1,
+		catalog: catalog,
+		spacing: 50 * time.Millisecond, //nolint:gomnd
+		wait:    2 * time.Second,       //nolint:gomnd
+		observe: 30 * time.Second,      //nolint:gomnd
+	}
+}
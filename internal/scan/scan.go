@@ -0,0 +1,305 @@
+// Package scan enumerates the modules on the bus by probing every address with a status query and
+// observing the traffic afterwards.
+//
+// Frames carry the segment of their destination only, so an answer is placed in the segment of the
+// outstanding probe of its module, however late it arrives. Modules only seen passively are placed in
+// their configured segment, or segment 0.
+package scan
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+// MaxModule is the highest module address probed.
+const MaxModule = 254
+
+const (
+	typeRelay  = "relay"  // reports relais
+	typeSensor = "sensor" // sends commands but reports no outputs
+)
+
+// Inventory lists the modules found, shaped like the devices section of the configuration.
+type Inventory struct {
+	Segments []Segment `yaml:"segments"`
+}
+
+type Segment struct {
+	ID      int      `yaml:"id"`
+	Modules []Module `yaml:"modules"`
+}
+
+// Module is a module found on the bus, commands are listed by their name from the command package.
+type Module struct {
+	ID        int      `yaml:"id"`
+	Name      string   `yaml:"name,omitempty"`
+	Type      string   `yaml:"type"`
+	Answered  bool     `yaml:"answered"`            // replied to a probe
+	Commands  []string `yaml:"commands,omitempty"`  // sent by the module
+	Addressed []string `yaml:"addressed,omitempty"` // sent to the module
+}
+
+type moduleKey struct {
+	segment int
+	module  int
+}
+
+// activity is what was seen of a module ID, in whatever segment.
+type activity struct {
+	sent      map[byte]bool
+	addressed map[byte]bool
+	relais    bool
+}
+
+// Scanner probes the bus and collects the modules seen, received frames are passed to Handle.
+type Scanner struct {
+	port   serial.Port
+	config *Config
+
+	mutex    sync.Mutex
+	probed   map[int]int // segment of the outstanding probe, by module
+	answered map[moduleKey]bool
+	seen     map[int]*activity
+}
+
+// New creates a scanner sending its probes through port.
+func New(port serial.Port, options ...Option) *Scanner {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return &Scanner{
+		port:     port,
+		config:   config,
+		probed:   make(map[int]int),
+		answered: make(map[moduleKey]bool),
+		seen:     make(map[int]*activity),
+	}
+}
+
+// Scan probes all segments and observes the traffic afterwards, it returns early once ctx is done.
+// A failed probe, e.g. while replaying a capture, stops probing but not observing.
+func (s *Scanner) Scan(ctx context.Context) error {
+	for _, segment := range s.segments() {
+		if err := s.probe(ctx, segment); err != nil {
+			log.Warnf("Probing stopped: %s", err)
+
+			break
+		}
+	}
+
+	log.Infof("Observing the bus for %s", s.config.observe)
+
+	return sleep(ctx, s.config.observe)
+}
+
+func (s *Scanner) segments() []int {
+	if len(s.config.segments) > 0 {
+		return s.config.segments
+	}
+
+	var segments []int
+
+	for _, module := range s.config.catalog.Modules() {
+		if !slices.Contains(segments, module.Segment) {
+			segments = append(segments, module.Segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		segments = []int{0}
+	}
+
+	return segments
+}
+
+// probe sends a status query to every address of a segment and waits for the late replies.
+func (s *Scanner) probe(ctx context.Context, segment int) error {
+	log.Infof("Probing segment %d", segment)
+
+	for module := 0; module <= MaxModule; module++ {
+		if byte(module) == s.config.source {
+			continue
+		}
+
+		pkt, err := command.NewPacket(s.config.source, byte(segment), byte(module), command.QueryAllRelais())
+		if err != nil {
+			return errors.Wrapf(err, "probe of module %d", module)
+		}
+
+		buf, err := pkt.Serialize()
+		if err != nil {
+			return errors.Wrapf(err, "probe of module %d", module)
+		}
+
+		// a probe of the same module in an earlier segment is no longer answered
+		s.setProbed(module, segment)
+
+		if err := s.port.Send(ctx, buf); err != nil {
+			return errors.Wrapf(err, "probe of module %d", module)
+		}
+
+		if err := sleep(ctx, s.config.spacing); err != nil {
+			return err
+		}
+	}
+
+	return sleep(ctx, s.config.wait)
+}
+
+func (s *Scanner) setProbed(module, segment int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.probed[module] = segment
+}
+
+// Handle records a received frame.
+func (s *Scanner) Handle(pkt *lcn.LcnPacket) {
+	if pkt.Invalid || pkt.Src == s.config.source {
+		return
+	}
+
+	cmd, _ := command.Decode(pkt)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	src := s.activity(int(pkt.Src))
+	src.sent[pkt.Cmd] = true
+
+	switch c := cmd.(type) {
+	case *command.StatusQuery:
+		if c.Response {
+			src.relais = true
+
+			if segment, ok := s.probed[int(pkt.Src)]; ok && pkt.Dst == s.config.source {
+				delete(s.probed, int(pkt.Src))
+
+				s.answered[moduleKey{segment: segment, module: int(pkt.Src)}] = true
+			}
+		}
+	case *command.RelaisStatus:
+		src.relais = true
+	}
+
+	dst := s.activity(int(pkt.Dst))
+	dst.addressed[pkt.Cmd] = true
+
+	if _, ok := cmd.(*command.RelaisCommand); ok {
+		dst.relais = true
+	}
+}
+
+// activity returns what was seen of a module, the mutex must be held.
+func (s *Scanner) activity(module int) *activity {
+	a, ok := s.seen[module]
+	if !ok {
+		a = &activity{sent: make(map[byte]bool), addressed: make(map[byte]bool)}
+		s.seen[module] = a
+	}
+
+	return a
+}
+
+// Inventory returns the modules that answered a probe or sent a frame, modules that were only addressed
+// are left out as the address may be a group.
+func (s *Scanner) Inventory() Inventory {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	found := make(map[moduleKey]bool, len(s.answered))
+	placed := make(map[int]bool)
+
+	for key := range s.answered {
+		found[key] = true
+		placed[key.module] = true
+	}
+
+	for module, a := range s.seen {
+		if len(a.sent) > 0 && !placed[module] {
+			found[moduleKey{segment: s.config.catalog.Segment(module), module: module}] = true
+		}
+	}
+
+	var inventory Inventory
+
+	for key := range found {
+		index := slices.IndexFunc(inventory.Segments, func(segment Segment) bool { return segment.ID == key.segment })
+		if index < 0 {
+			inventory.Segments = append(inventory.Segments, Segment{ID: key.segment})
+			index = len(inventory.Segments) - 1
+		}
+
+		inventory.Segments[index].Modules = append(inventory.Segments[index].Modules, s.module(key))
+	}
+
+	slices.SortFunc(inventory.Segments, func(a, b Segment) int { return a.ID - b.ID })
+
+	for _, segment := range inventory.Segments {
+		slices.SortFunc(segment.Modules, func(a, b Module) int { return a.ID - b.ID })
+	}
+
+	return inventory
+}
+
+// module describes a found module, the mutex must be held.
+func (s *Scanner) module(key moduleKey) Module {
+	module := Module{
+		ID:       key.module,
+		Answered: s.answered[key],
+		Type:     typeSensor,
+	}
+
+	for _, configured := range s.config.catalog.Modules() {
+		if configured.Segment == key.segment && configured.Module == key.module {
+			module.Name = configured.Name
+		}
+	}
+
+	a := s.activity(key.module)
+	if a.relais {
+		module.Type = typeRelay
+	}
+
+	module.Commands = names(a.sent)
+	module.Addressed = names(a.addressed)
+
+	return module
+}
+
+func names(codes map[byte]bool) []string {
+	sorted := make([]byte, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+
+	slices.Sort(sorted)
+
+	var names []string
+	for _, code := range sorted {
+		names = append(names, command.Name(code))
+	}
+
+	return names
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "scan cancelled")
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package scan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/scan"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+// fakeBus answers status queries of the modules in their segment right away, late ones once the next
+// segment is probed. Answers carry the segment of the destination like on the bus.
+type fakeBus struct {
+	t       *testing.T
+	modules map[[2]byte]bool // segment, module
+	late    map[[2]byte]bool
+	scanner *scan.Scanner
+	probes  int
+	segment byte
+	held    []*lcn.LcnPacket
+}
+
+func (b *fakeBus) Run(context.Context, context.CancelFunc, chunker.EjectFunc) {}
+
+func (b *fakeBus) Send(_ context.Context, buf []byte) error {
+	p, err := lcn.Deserialize(buf)
+	require.NoError(b.t, err)
+
+	pkt, ok := p.(*lcn.LcnPacket)
+	require.True(b.t, ok)
+
+	require.Equal(b.t, []byte{0xFB, 0xFF}, pkt.Payload, "queries all relais")
+
+	b.probes++
+
+	if pkt.Seg != b.segment {
+		for _, answer := range b.held {
+			b.scanner.Handle(answer)
+		}
+
+		b.segment, b.held = pkt.Seg, nil
+	}
+
+	answer := b.packet(0, pkt.Dst, pkt.Src, &command.StatusQuery{Response: true})
+
+	switch address := [2]byte{pkt.Seg, pkt.Dst}; {
+	case b.modules[address]:
+		b.scanner.Handle(answer)
+	case b.late[address]:
+		b.held = append(b.held, answer)
+	}
+
+	return nil
+}
+
+func (b *fakeBus) Request(ctx context.Context, buf []byte, _ serial.Matcher) (packet.Packet, error) {
+	return nil, b.Send(ctx, buf)
+}
+
+func (b *fakeBus) QueueDepth() int {
+	return 0
+}
+
+func (b *fakeBus) State() serial.State {
+	return serial.StateConnected
+}
+
+func (b *fakeBus) OnStateChange(callback serial.StateCallback) {
+	callback(serial.StateConnected)
+}
+
+func (b *fakeBus) Stats() serial.Stats {
+	return serial.Stats{}
+}
+
+func (b *fakeBus) packet(seg, src, dst byte, cmd command.Command) *lcn.LcnPacket {
+	pkt, err := command.NewPacket(src, seg, dst, cmd)
+	require.NoError(b.t, err)
+
+	return pkt
+}
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{
+			{ID: 0, Modules: []config.ModuleConfig{{ID: 33, Name: "Licht A"}, {ID: 11}}},
+			{ID: 5},
+		},
+	})
+	require.NoError(t, err)
+
+	bus := &fakeBus{
+		t:       t,
+		modules: map[[2]byte]bool{{0, 33}: true, {5, 35}: true},
+		late:    map[[2]byte]bool{{0, 40}: true},
+	}
+	bus.scanner = scan.New(bus,
+		scan.Source(2),
+		scan.Catalog(catalog),
+		scan.Segments(0, 5),
+		scan.Spacing(0),
+		scan.Wait(0),
+		scan.Observe(0),
+	)
+
+	require.NoError(t, bus.scanner.Scan(context.Background()))
+	assert.Equal(t, 2*254, bus.probes, "all addresses but the own one in both segments")
+
+	// traffic observed after probing, a late answer belongs to the last probe of its module
+	bus.scanner.Handle(bus.packet(0, 41, 2, &command.StatusQuery{Response: true}))
+	bus.scanner.Handle(&lcn.LcnPacket{Src: 35, Dst: 2, Cmd: command.CodeStatusReport, Payload: []byte{0x01, 0x64}})
+	bus.scanner.Handle(bus.packet(0, 11, 33, &command.RelaisCommand{Outputs: [8]command.RelaisAction{command.RelaisOn}}))
+	bus.scanner.Handle(&lcn.LcnPacket{Src: 12, Dst: 4, Cmd: 0x22, Invalid: true})
+
+	assert.Equal(t, scan.Inventory{Segments: []scan.Segment{
+		{ID: 0, Modules: []scan.Module{
+			{ID: 11, Type: "sensor", Commands: []string{"relais"}},
+			{ID: 33, Name: "Licht A", Type: "relay", Answered: true, Commands: []string{"statusQuery"}, Addressed: []string{"relais"}},
+			{ID: 40, Type: "relay", Answered: true, Commands: []string{"statusQuery"}},
+		}},
+		{ID: 5, Modules: []scan.Module{
+			{ID: 35, Type: "relay", Answered: true, Commands: []string{"statusReport", "statusQuery"}},
+			{ID: 41, Type: "relay", Answered: true, Commands: []string{"statusQuery"}},
+		}},
+	}}, bus.scanner.Inventory())
+}
@@ -0,0 +1,123 @@
+package lcn
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	checksumIndex = 2
+
+	// DefaultChecksum is the checksum matching the frames seen so far.
+	DefaultChecksum = "yali"
+)
+
+var ErrUnknownChecksum = errors.New("unknown checksum")
+
+// ChecksumFunc calculates the checksum of a frame, skipping the checksum byte itself.
+type ChecksumFunc func(buf []byte) byte
+
+// Checksums are the checksum variants by name. The algorithm is not documented, "yali" is derived
+// from Yali and matches all frames seen so far, the others are experiments to compare against.
+//
+//nolint:gochecknoglobals
+var Checksums = map[string]ChecksumFunc{
+	"yali": YaliChecksum,
+	"sum":  SumChecksum,
+	"xor":  XorChecksum,
+	"crc8": Crc8Checksum,
+}
+
+// ChecksumNames returns the names of all checksum variants, sorted.
+func ChecksumNames() []string {
+	names := make([]string, 0, len(Checksums))
+	for name := range Checksums {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ChecksumByName returns the checksum variant called name.
+func ChecksumByName(name string) (ChecksumFunc, error) {
+	checksum, ok := Checksums[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownChecksum, "%q, known are %v", name, ChecksumNames())
+	}
+
+	return checksum, nil
+}
+
+// YaliChecksum adds each byte and rotates the sum by two bits within 9 bits, folding the carry back.
+func YaliChecksum(buf []byte) byte {
+	var checksum byte = 0
+
+	for i, b := range buf {
+		if i == checksumIndex {
+			continue
+		}
+
+		tmp := int(b) + int(checksum)
+		tmp2 := ((tmp&0x7F)<<2 | (tmp&0x180)>>7)
+		if tmp2 > 0xFF {
+			tmp2 -= 0xFF
+		}
+		checksum = byte(tmp2)
+	}
+
+	return checksum
+}
+
+// SumChecksum is the sum of all bytes modulo 256.
+func SumChecksum(buf []byte) byte {
+	var checksum byte
+
+	for i, b := range buf {
+		if i != checksumIndex {
+			checksum += b
+		}
+	}
+
+	return checksum
+}
+
+// XorChecksum is the xor of all bytes.
+func XorChecksum(buf []byte) byte {
+	var checksum byte
+
+	for i, b := range buf {
+		if i != checksumIndex {
+			checksum ^= b
+		}
+	}
+
+	return checksum
+}
+
+// Crc8Checksum is a CRC-8 with polynomial 0x07 and no reflection.
+func Crc8Checksum(buf []byte) byte {
+	const polynomial = 0x07
+
+	var crc byte
+
+	for i, b := range buf {
+		if i == checksumIndex {
+			continue
+		}
+
+		crc ^= b
+
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ polynomial
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
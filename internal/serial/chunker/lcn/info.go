@@ -0,0 +1,94 @@
+package lcn
+
+import (
+	"encoding/json"
+)
+
+// Bits of the INFO byte. Only the length is known, the other bits are named after guesses no capture or
+// documentation confirms yet; lcnMonitor -info tallies them against real traffic.
+const (
+	InfoAckRequested = 0x01 // guess: the target is asked to acknowledge
+	InfoFromModule   = 0x02 // guess: set on frames sent by modules rather than by the PC
+	InfoLength       = 0x0C // frame length, see lengthMapping
+	InfoPriority     = 0x30 // guess: bus priority
+	InfoRouted       = 0xC0 // guess: hops through segment couplers
+)
+
+// InfoFields are the bits of the INFO byte, all but Length named after unconfirmed guesses.
+type InfoFields struct {
+	Length       int  // length of the frame in bytes
+	AckRequested bool // unconfirmed
+	FromModule   bool // unconfirmed
+	Priority     byte // unconfirmed, 0-3
+	Routed       byte // unconfirmed, 0-3
+}
+
+// InfoFields decodes the INFO byte.
+func (lcn *LcnPacket) InfoFields() InfoFields {
+	return DecodeInfo(lcn.Info)
+}
+
+func DecodeInfo(info byte) InfoFields {
+	return InfoFields{
+		Length:       lengthMapping[info&InfoLength>>2],
+		AckRequested: info&InfoAckRequested != 0,
+		FromModule:   info&InfoFromModule != 0,
+		Priority:     info & InfoPriority >> 4,
+		Routed:       info & InfoRouted >> 6,
+	}
+}
+
+// Encode returns the INFO byte, an unknown length is left 0 as Serialize sets it from the payload.
+func (f InfoFields) Encode() byte {
+	var info byte
+
+	for code, length := range lengthMapping {
+		if length == f.Length {
+			info |= code << 2
+		}
+	}
+
+	if f.AckRequested {
+		info |= InfoAckRequested
+	}
+
+	if f.FromModule {
+		info |= InfoFromModule
+	}
+
+	info |= f.Priority << 4 & InfoPriority
+	info |= f.Routed << 6 & InfoRouted
+
+	return info
+}
+
+// jsonPacket is LcnPacket without its JSON methods.
+type jsonPacket LcnPacket
+
+// MarshalJSON adds the decoded INFO bits to the raw INFO byte.
+func (lcn LcnPacket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonPacket
+		InfoFields InfoFields
+	}{jsonPacket(lcn), lcn.InfoFields()})
+}
+
+// UnmarshalJSON takes the INFO byte from InfoFields if given, from Info otherwise.
+func (lcn *LcnPacket) UnmarshalJSON(data []byte) error {
+	var in struct {
+		*jsonPacket
+		InfoFields *InfoFields
+	}
+
+	in.jsonPacket = (*jsonPacket)(lcn)
+
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.InfoFields != nil {
+		lcn.Info = in.InfoFields.Encode()
+	}
+
+	return nil
+}
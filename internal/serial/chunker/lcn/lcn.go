@@ -16,10 +16,14 @@ const (
 var (
 	ErrLcnPacketIncomplete      = errors.Wrap(packet.ErrPacketIncomplete, "LCN Packet to short")
 	ErrLcnPacketInvalid         = errors.Wrap(packet.ErrPacketInvalid, "LCN Packet Invalid")
-	ErrLcnPacketInvalidChecksum = errors.Wrap(packet.ErrPacketInvalid, "LCN Checksum invalid")
+	ErrLcnPacketInvalidChecksum = errors.Wrap(packet.ErrPacketChecksum, "LCN Checksum invalid")
 )
 
-var _ packet.Packet = &LcnPacket{}
+var (
+	_ packet.Packet   = &LcnPacket{}
+	_ packet.Flagged  = &LcnPacket{}
+	_ packet.Verbatim = &LcnPacket{}
+)
 
 var lengthMapping = map[byte]int{
 	0b00: 6,
@@ -36,9 +40,36 @@ type LcnPacket struct {
 	Dst      byte
 	Cmd      byte
 	Payload  []byte
+	Invalid  bool `json:",omitempty"` // checksum did not match, see AcceptInvalid
+}
+
+// Codec deserializes and serializes LCN frames with a configurable checksum.
+type Codec struct {
+	checksum      ChecksumFunc
+	acceptInvalid bool
+}
+
+//nolint:gochecknoglobals
+var defaultCodec = NewCodec()
+
+func NewCodec(options ...Option) *Codec {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	return &Codec{
+		checksum:      config.checksum,
+		acceptInvalid: config.acceptInvalid,
+	}
 }
 
 func Deserialize(buf []byte) (packet.Packet, error) {
+	return defaultCodec.Deserialize(buf)
+}
+
+func (c *Codec) Deserialize(buf []byte) (packet.Packet, error) {
 	if len(buf) < MIN_LCN_PACKET_LENGTH {
 		return nil, ErrLcnPacketIncomplete
 	}
@@ -67,18 +98,28 @@ func Deserialize(buf []byte) (packet.Packet, error) {
 		return nil, ErrLcnPacketInvalid
 	}
 
-	if checksum := calcChecksum(buf); checksum != lcn.Checksum {
+	if checksum := c.checksum(buf); checksum != lcn.Checksum {
 		log.Debugf("Wrong Checksum is %x expected: %x", lcn.Checksum, checksum)
 
-		return nil, ErrLcnPacketInvalidChecksum
+		if !c.acceptInvalid {
+			return nil, ErrLcnPacketInvalidChecksum
+		}
+
+		lcn.Invalid = true
 	}
 
 	log.Debugf("Deserialized LCN Packet {%s}", lcn.ToString())
 	return lcn, nil
 }
 
-// this function sets checksum and length information by itself
+// this function sets checksum and length information by itself,
+// the other bits of Info are kept, see InfoFields
 func (lcn *LcnPacket) Serialize() ([]byte, error) {
+	return defaultCodec.Serialize(lcn)
+}
+
+// Serialize sets checksum and length information by itself.
+func (c *Codec) Serialize(lcn *LcnPacket) ([]byte, error) {
 	bufLen := MIN_LCN_PACKET_LENGTH + len(lcn.Payload)
 	buf := make([]byte, bufLen)
 	buf[0] = mirrorSrc(lcn.Src)
@@ -102,7 +143,7 @@ func (lcn *LcnPacket) Serialize() ([]byte, error) {
 		return nil, ErrLcnPacketInvalid
 	}
 
-	buf[2] = calcChecksum(buf)
+	buf[2] = c.checksum(buf)
 
 	return buf, nil
 }
@@ -117,6 +158,23 @@ func (lcn *LcnPacket) ToNiceString() string {
 		lcn.Src, lcn.Seg, lcn.Dst, lcn.Cmd, hex.EncodeToString(lcn.Payload))
 }
 
+// Verbatim implements packet.Verbatim, it returns the frame with the checksum it carries instead of computing one.
+func (lcn *LcnPacket) Verbatim() ([]byte, error) {
+	buf, err := lcn.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	buf[2] = lcn.Checksum
+
+	return buf, nil
+}
+
+// ChecksumInvalid implements packet.Flagged.
+func (lcn *LcnPacket) ChecksumInvalid() bool {
+	return lcn.Invalid
+}
+
 func mirrorSrc(in byte) byte {
 	src := byte(0)
 	for p := 0; p < 8; p++ {
@@ -125,22 +183,3 @@ func mirrorSrc(in byte) byte {
 	}
 	return src
 }
-
-func calcChecksum(buf []byte) byte {
-	var checksum byte = 0
-
-	for i, b := range buf {
-		if i == 2 {
-			continue
-		}
-
-		tmp := int(b) + int(checksum)
-		tmp2 := ((tmp&0x7F)<<2 | (tmp&0x180)>>7)
-		if tmp2 > 0xFF {
-			tmp2 -= 0xFF
-		}
-		checksum = byte(tmp2)
-	}
-
-	return checksum
-}
@@ -1,12 +1,20 @@
 package lcn_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
 )
 
@@ -14,6 +22,7 @@ func TestErrors(t *testing.T) {
 	assert.ErrorIs(t, lcn.ErrLcnPacketIncomplete, packet.ErrPacketIncomplete)
 	assert.ErrorIs(t, lcn.ErrLcnPacketInvalid, packet.ErrPacketInvalid)
 	assert.ErrorIs(t, lcn.ErrLcnPacketInvalidChecksum, packet.ErrPacketInvalid)
+	assert.ErrorIs(t, lcn.ErrLcnPacketInvalidChecksum, packet.ErrPacketChecksum)
 }
 
 func TestDeserialize(t *testing.T) {
@@ -149,3 +158,145 @@ func TestSerialize(t *testing.T) {
 		})
 	}
 }
+
+// TestCaptures chunks the received bytes of every capture in testdata and expects the recorded frames.
+// synthetic.capture was assembled from the byte sequences above, its timestamps are made up.
+func TestCaptures(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.capture")
+	require.NoError(t, err)
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			reader, err := capture.Open(file)
+			require.NoError(t, err)
+
+			defer reader.Close()
+
+			var expected, frames [][]byte
+
+			c := chunker.NewChunker(lcn.Deserialize, 6)
+
+			for {
+				record, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				require.NoError(t, err)
+
+				if record.Direction() != serial.DirectionRx {
+					continue
+				}
+
+				if record.Frame != nil {
+					expected = append(expected, record.Frame)
+				}
+
+				c.Collect(record.Raw, func(pkt packet.Packet) {
+					buf, err := pkt.Serialize()
+					assert.NoError(t, err)
+
+					frames = append(frames, buf)
+				})
+			}
+
+			assert.NotEmpty(t, expected)
+			assert.Equal(t, expected, frames)
+		})
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	frame := []byte{0x80, 0x00, 0xff, 0x2, 0x4, 0x5}
+
+	tests := []struct {
+		name     string
+		input    []byte
+		checksum byte
+	}{
+		{name: "yali", input: frame, checksum: 0xd5},
+		{name: "sum", input: frame, checksum: 0x8b},
+		{name: "xor", input: frame, checksum: 0x83},
+		// the check value of CRC-8, the byte at the position of the checksum is skipped
+		{name: "crc8", input: []byte("12X3456789"), checksum: 0xf4},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, err := lcn.ChecksumByName(tt.name)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.checksum, checksum(tt.input))
+		})
+	}
+
+	_, err := lcn.ChecksumByName("crc32")
+	assert.ErrorIs(t, err, lcn.ErrUnknownChecksum)
+	assert.Equal(t, []string{"crc8", "sum", "xor", "yali"}, lcn.ChecksumNames())
+}
+
+func TestCodec(t *testing.T) {
+	frame := []byte{0xa8, 0x06, 0x76, 0x00, 0x04, 0x68, 0x30, 0x00}
+	expected := &lcn.LcnPacket{Src: 0x15, Info: 0x06, Checksum: 0x76, Seg: 0, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x00}}
+
+	_, err := lcn.Deserialize(frame)
+	assert.ErrorIs(t, err, lcn.ErrLcnPacketInvalidChecksum)
+
+	pkt, err := lcn.NewCodec(lcn.AcceptInvalid(true)).Deserialize(frame)
+	require.NoError(t, err)
+
+	expected.Invalid = true
+	assert.Equal(t, expected, pkt)
+
+	// serializing fixes the checksum
+	buf, err := pkt.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x75), buf[2])
+
+	// while the verbatim frame keeps it
+	buf, err = pkt.(*lcn.LcnPacket).Verbatim()
+	require.NoError(t, err)
+	assert.Equal(t, frame, buf)
+
+	sum := lcn.NewCodec(lcn.Checksum(lcn.SumChecksum))
+
+	buf, err = sum.Serialize(expected)
+	require.NoError(t, err)
+	assert.Equal(t, lcn.SumChecksum(buf), buf[2])
+
+	pkt, err = sum.Deserialize(buf)
+	require.NoError(t, err)
+	assert.False(t, pkt.(*lcn.LcnPacket).Invalid)
+}
+
+func TestInfoFields(t *testing.T) {
+	assert.Equal(t, lcn.InfoFields{Length: 8, FromModule: true}, lcn.DecodeInfo(0x06))
+	assert.Equal(t, lcn.InfoFields{Length: 20, FromModule: true, Routed: 1}, lcn.DecodeInfo(0x4e))
+
+	for info := 0; info <= 0xff; info++ {
+		assert.Equal(t, byte(info), lcn.DecodeInfo(byte(info)).Encode())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	pkt := &lcn.LcnPacket{Src: 0x15, Info: 0x06, Checksum: 0x75, Seg: 0, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x00}}
+
+	buf, err := json.Marshal(pkt)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Src":21,"Info":6,"Checksum":117,"Seg":0,"Dst":4,"Cmd":104,"Payload":"MAA=",
+		"InfoFields":{"Length":8,"AckRequested":false,"FromModule":true,"Priority":0,"Routed":0}
+	}`, string(buf))
+
+	var decoded lcn.LcnPacket
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.Equal(t, *pkt, decoded)
+
+	// the fields take precedence over the raw byte
+	require.NoError(t, json.Unmarshal([]byte(`{"Src":1,"Info":6,"InfoFields":{"AckRequested":true}}`), &decoded))
+	assert.Equal(t, byte(0x01), decoded.Info)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Src":1,"Info":6}`), &decoded))
+	assert.Equal(t, byte(0x06), decoded.Info)
+}
@@ -0,0 +1,31 @@
+package lcn
+
+type (
+	Option func(*Config)
+	Config struct {
+		checksum      ChecksumFunc
+		acceptInvalid bool
+	}
+)
+
+// Checksum replaces the checksum used to validate and create frames.
+func Checksum(checksum ChecksumFunc) Option {
+	return func(c *Config) {
+		c.checksum = checksum
+	}
+}
+
+// AcceptInvalid makes frames with a wrong checksum deserialize flagged as Invalid instead of failing.
+// The checksum is what tells frames from noise, so stale bytes should be dropped by the gap between
+// frames when accepting invalid ones.
+func AcceptInvalid(acceptInvalid bool) Option {
+	return func(c *Config) {
+		c.acceptInvalid = acceptInvalid
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		checksum: YaliChecksum,
+	}
+}
@@ -0,0 +1,50 @@
+package shade
+
+import (
+	"time"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		reversePause time.Duration
+		clock        func() time.Time
+		afterFunc    func(time.Duration, func()) Timer
+	}
+)
+
+// Timer is a pending stop of a move, *time.Timer implements it.
+type Timer interface {
+	Stop() bool
+}
+
+// ReversePause sets how long both relais stay off before a moving shade is reversed.
+func ReversePause(pause time.Duration) Option {
+	return func(c *Config) {
+		c.reversePause = pause
+	}
+}
+
+// Clock replaces time.Now for the position estimation.
+func Clock(clock func() time.Time) Option {
+	return func(c *Config) {
+		c.clock = clock
+	}
+}
+
+// AfterFunc replaces time.AfterFunc for the timed stops.
+func AfterFunc(afterFunc func(time.Duration, func()) Timer) Option {
+	return func(c *Config) {
+		c.afterFunc = afterFunc
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		reversePause: 500 * time.Millisecond, //nolint:gomnd
+		clock:        time.Now,
+		afterFunc: func(d time.Duration, f func()) Timer {
+			return time.AfterFunc(d, f)
+		},
+	}
+}
@@ -0,0 +1,427 @@
+// Package shade drives shades by their pair of relais and estimates their position from the travel times.
+//
+// Both relais are always switched by the same frame and a shade is reversed only after its relais were off for a
+// pause, so the motor never gets both directions at once. Relais commands of anybody else touching the
+// relais of a shade are refused by Check.
+package shade
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+const (
+	Open   = 100.0 // position of an open shade in percent
+	Closed = 0.0
+
+	// overrun extends a move to an end position, so the shade gets there even if the estimate is off
+	overrun = 0.1
+)
+
+var (
+	ErrInterlock       = errors.New("relais of a shade are switched by its controller only")
+	ErrNoTravelTimes   = errors.New("shade has no travel times")
+	ErrPositionUnknown = errors.New("position unknown, open or close the shade fully first")
+	ErrInvalidPosition = errors.New("position out of range")
+)
+
+// SendFunc sends a relais command to the module of a shade, a command sent later for the same shade supersedes it.
+type SendFunc func(ctx context.Context, shade monitor.Shade, cmd *command.RelaisCommand)
+
+// Controller moves a single shade and tracks its position.
+type Controller struct {
+	shade        monitor.Shade
+	send         SendFunc
+	reversePause time.Duration
+	clock        func() time.Time
+	afterFunc    func(time.Duration, func()) Timer
+
+	mutex      sync.Mutex
+	moving     state.ShadeState               // as observed on the relais
+	driving    state.ShadeState               // as last sent, the relais may not have reported it yet
+	released   map[state.ShadeState]time.Time // by direction, when its relais was last switched off
+	since      time.Time                      // start of the current move
+	position   float64                        // percent open at since
+	known      bool
+	timer      Timer
+	generation int // of the latest move, outdated timers do nothing
+}
+
+// Shade returns the configuration of the shade.
+func (c *Controller) Shade() monitor.Shade {
+	return c.shade
+}
+
+// Open moves the shade up, with travel times it is stopped once it is open.
+// Commands of the move, including the timed stop, are sent with ctx.
+func (c *Controller) Open(ctx context.Context) {
+	c.move(ctx, state.ShadeOpening, c.endTravel(c.shade.TravelUp))
+}
+
+// Close moves the shade down, with travel times it is stopped once it is closed.
+func (c *Controller) Close(ctx context.Context) {
+	c.move(ctx, state.ShadeClosing, c.endTravel(c.shade.TravelDown))
+}
+
+// Stop switches both relais off.
+func (c *Controller) Stop(ctx context.Context) {
+	c.mutex.Lock()
+	c.cancel()
+	c.mutex.Unlock()
+
+	c.stop(ctx)
+}
+
+// stop switches both relais off, the pause before reversing starts when the frame is sent.
+func (c *Controller) stop(ctx context.Context) {
+	c.mutex.Lock()
+	now := c.clock()
+	c.release(c.driving, now)
+	c.release(c.moving, now)
+	c.driving = state.ShadeStopped
+	c.mutex.Unlock()
+
+	c.send(ctx, c.shade, relais(c.shade, state.ShadeStopped))
+}
+
+// release records that the relais of a direction were switched off, the mutex must be held.
+func (c *Controller) release(direction state.ShadeState, now time.Time) {
+	if direction != state.ShadeStopped {
+		c.released[direction] = now
+	}
+}
+
+// SetPosition moves the shade to a position in percent, 100 is open. End positions can always be set,
+// anything between needs a known position.
+func (c *Controller) SetPosition(ctx context.Context, target float64) error {
+	if !c.shade.Travels() {
+		return ErrNoTravelTimes
+	}
+
+	if target < Closed || target > Open {
+		return errors.Wrapf(ErrInvalidPosition, "%g", target)
+	}
+
+	switch target {
+	case Open:
+		c.Open(ctx)
+
+		return nil
+	case Closed:
+		c.Close(ctx)
+
+		return nil
+	}
+
+	current, known := c.Position()
+	if !known {
+		return ErrPositionUnknown
+	}
+
+	// a shade already there is left alone, moving or not
+	switch {
+	case target > current:
+		c.move(ctx, state.ShadeOpening, travel(target-current, c.shade.TravelUp))
+	case target < current:
+		c.move(ctx, state.ShadeClosing, travel(current-target, c.shade.TravelDown))
+	}
+
+	return nil
+}
+
+// Position returns the estimated position in percent and whether it is known.
+func (c *Controller) Position() (float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.estimate(c.clock())
+}
+
+// Moving returns the direction the shade moves in, as observed on its relais.
+func (c *Controller) Moving() state.ShadeState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.moving
+}
+
+// move starts moving in a direction for the given duration, forever if it is zero. A shade sent or seen
+// moving the other way is stopped first and reversed after the pause, one whose other relais was switched
+// off less than the pause ago waits for the rest of it. Frames count once sent, without waiting for the
+// relais to report, so a reversal right after a move is paused as well.
+func (c *Controller) move(ctx context.Context, direction state.ShadeState, duration time.Duration) {
+	reverse := state.ShadeOpening
+	if direction == state.ShadeOpening {
+		reverse = state.ShadeClosing
+	}
+
+	c.mutex.Lock()
+	c.cancel()
+	generation := c.generation
+	running := c.driving == reverse || c.moving == reverse
+	rest := c.released[reverse].Add(c.reversePause).Sub(c.clock())
+	c.mutex.Unlock()
+
+	start := func() {
+		c.start(ctx, generation, direction, duration)
+	}
+
+	switch {
+	case running:
+		c.stop(ctx)
+		c.schedule(generation, c.reversePause, start)
+	case rest > 0:
+		c.schedule(generation, rest, start)
+	default:
+		start()
+	}
+}
+
+func (c *Controller) start(ctx context.Context, generation int, direction state.ShadeState, duration time.Duration) {
+	c.mutex.Lock()
+	if generation != c.generation {
+		c.mutex.Unlock()
+
+		return
+	}
+
+	c.driving = direction
+	c.mutex.Unlock()
+
+	c.send(ctx, c.shade, relais(c.shade, direction))
+
+	if duration > 0 {
+		c.schedule(generation, duration, func() {
+			if c.current(generation) {
+				c.stop(ctx)
+			}
+		})
+	}
+}
+
+func (c *Controller) schedule(generation int, d time.Duration, f func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation == c.generation {
+		c.timer = c.afterFunc(d, f)
+	}
+}
+
+func (c *Controller) current(generation int) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return generation == c.generation
+}
+
+// cancel drops the pending stop or reversal, the mutex must be held.
+func (c *Controller) cancel() {
+	c.generation++
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// observe takes the direction from the relais, whoever switched them.
+func (c *Controller) observe(moving state.ShadeState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if moving == c.moving {
+		return
+	}
+
+	now := c.clock()
+
+	c.release(c.moving, now)
+
+	c.position, c.known = c.estimate(now)
+	c.moving, c.since = moving, now
+}
+
+// estimate integrates the current move up to now, a move lasting the full travel time reaches a known end.
+func (c *Controller) estimate(now time.Time) (float64, bool) {
+	if !c.shade.Travels() {
+		return c.position, c.known
+	}
+
+	elapsed := now.Sub(c.since)
+
+	switch c.moving {
+	case state.ShadeOpening:
+		if elapsed >= c.shade.TravelUp {
+			return Open, true
+		}
+
+		return min(Open, c.position+Open*elapsed.Seconds()/c.shade.TravelUp.Seconds()), c.known
+	case state.ShadeClosing:
+		if elapsed >= c.shade.TravelDown {
+			return Closed, true
+		}
+
+		return max(Closed, c.position-Open*elapsed.Seconds()/c.shade.TravelDown.Seconds()), c.known
+	case state.ShadeStopped:
+	}
+
+	return c.position, c.known
+}
+
+func (c *Controller) endTravel(full time.Duration) time.Duration {
+	if !c.shade.Travels() {
+		return 0
+	}
+
+	return full + time.Duration(float64(full)*overrun)
+}
+
+// travel returns the time to move by percent.
+func travel(percent float64, full time.Duration) time.Duration {
+	return time.Duration(float64(full) * percent / Open)
+}
+
+// relais builds the only relais commands sent to a shade, both relais are switched at once and at most one is on.
+func relais(shade monitor.Shade, direction state.ShadeState) *command.RelaisCommand {
+	cmd := new(command.RelaisCommand)
+
+	switch direction {
+	case state.ShadeOpening:
+		cmd.Outputs[shade.Output], cmd.Outputs[shade.Down] = command.RelaisOn, command.RelaisOff
+	case state.ShadeClosing:
+		cmd.Outputs[shade.Output], cmd.Outputs[shade.Down] = command.RelaisOff, command.RelaisOn
+	case state.ShadeStopped:
+		cmd.Outputs[shade.Output], cmd.Outputs[shade.Down] = command.RelaisOff, command.RelaisOff
+	}
+
+	return cmd
+}
+
+// Shades holds the controllers of all configured shades and keeps them informed about their relais.
+type Shades struct {
+	catalog     *monitor.Catalog
+	registry    *state.Registry
+	controllers map[state.Key]*Controller // by up relais
+	relais      map[state.Key]*Controller // by both relais
+}
+
+// New creates controllers for all shades of the catalog, sending their commands with send.
+func New(catalog *monitor.Catalog, registry *state.Registry, send SendFunc, options ...Option) *Shades {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	s := &Shades{
+		catalog:     catalog,
+		registry:    registry,
+		controllers: make(map[state.Key]*Controller),
+		relais:      make(map[state.Key]*Controller),
+	}
+
+	for _, shade := range catalog.Devices().Shades {
+		c := &Controller{
+			shade:        shade,
+			send:         send,
+			reversePause: config.reversePause,
+			clock:        config.clock,
+			afterFunc:    config.afterFunc,
+			released:     make(map[state.ShadeState]time.Time),
+			since:        config.clock(),
+		}
+
+		up := state.Key{Segment: shade.Segment, Module: shade.Module, Output: shade.Output}
+		down := state.Key{Segment: shade.Segment, Module: shade.Module, Output: shade.Down}
+
+		s.controllers[up] = c
+		s.relais[up] = c
+		s.relais[down] = c
+	}
+
+	registry.Subscribe(s.onState)
+
+	return s
+}
+
+// Get returns the controller of a shade by its up relais.
+func (s *Shades) Get(segment, module, output int) (*Controller, bool) {
+	c, ok := s.controllers[state.Key{Segment: segment, Module: module, Output: output}]
+
+	return c, ok
+}
+
+// Check refuses relais commands switching a relais of a shade, those are up to its controller. Relais
+// commands to a module driving shades which cannot be decoded are refused as well, they might switch any relais.
+func (s *Shades) Check(pkt *lcn.LcnPacket) error {
+	// segment 0 is the own segment of the module
+	segment := int(pkt.Seg)
+	if segment == 0 {
+		segment = s.catalog.Segment(int(pkt.Dst))
+	}
+
+	cmd, err := command.Decode(pkt)
+	if err != nil {
+		return nil
+	}
+
+	relaisCmd, ok := cmd.(*command.RelaisCommand)
+	if !ok {
+		if pkt.Cmd == command.CodeRelais && s.drivesShades(segment, int(pkt.Dst)) {
+			return errors.Wrapf(ErrInterlock, "undecodable relais command to module %d: % X", pkt.Dst, pkt.Payload)
+		}
+
+		return nil
+	}
+
+	for output, action := range relaisCmd.Outputs {
+		if action == command.RelaisNoChange {
+			continue
+		}
+
+		if c, ok := s.Owner(segment, int(pkt.Dst), output); ok {
+			return errors.Wrapf(ErrInterlock, "output %d belongs to %s", output+1, c.shade.Name)
+		}
+	}
+
+	return nil
+}
+
+// drivesShades reports whether a relais of the module drives a shade.
+func (s *Shades) drivesShades(segment, module int) bool {
+	for key := range s.relais {
+		if key.Segment == segment && key.Module == module {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Owner returns the controller of the shade driven by a relais.
+func (s *Shades) Owner(segment, module, output int) (*Controller, bool) {
+	c, ok := s.relais[state.Key{Segment: segment, Module: module, Output: output}]
+
+	return c, ok
+}
+
+func (s *Shades) onState(events []state.Event) {
+	seen := make(map[*Controller]bool)
+
+	for _, event := range events {
+		if c, ok := s.relais[event.Output.Key]; ok && !seen[c] {
+			seen[c] = true
+
+			c.observe(s.registry.Shade(c.shade))
+		}
+	}
+}
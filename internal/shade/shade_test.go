@@ -0,0 +1,269 @@
+package shade_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/shade"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+type fakeTimer struct {
+	duration time.Duration
+	f        func()
+	stopped  bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.stopped = true
+
+	return true
+}
+
+type fixture struct {
+	now    time.Time
+	timers []*fakeTimer
+	sent   []*command.RelaisCommand
+	silent bool // sent commands are not reported back
+}
+
+// fire runs the latest timer after the given time passed.
+func (f *fixture) fire(t *testing.T, passed time.Duration) {
+	t.Helper()
+
+	require.NotEmpty(t, f.timers)
+
+	timer := f.timers[len(f.timers)-1]
+	require.False(t, timer.stopped)
+
+	f.now = f.now.Add(passed)
+	timer.f()
+}
+
+func (f *fixture) last() [2]command.RelaisAction {
+	cmd := f.sent[len(f.sent)-1]
+
+	return [2]command.RelaisAction{cmd.Outputs[0], cmd.Outputs[1]}
+}
+
+func newShades(t *testing.T) (*shade.Shades, *fixture) {
+	t.Helper()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{{
+			Modules: []config.ModuleConfig{{ID: 31, Outputs: []config.OutputConfig{
+				{ID: 1, Down: 2, Type: "shade", Name: "Shade", TravelUp: 20 * time.Second, TravelDown: 10 * time.Second},
+				{ID: 3, Type: "relay", Name: "Light"},
+			}}},
+		}},
+	})
+	require.NoError(t, err)
+
+	f := &fixture{now: time.Date(2023, 3, 5, 18, 0, 0, 0, time.UTC)}
+	registry := state.NewRegistry(state.Catalog(catalog), state.Clock(func() time.Time { return f.now }))
+
+	// sent commands are seen on the bus like the bridge does
+	send := func(_ context.Context, s monitor.Shade, cmd *command.RelaisCommand) {
+		require.False(t, cmd.Outputs[s.Output] == command.RelaisOn && cmd.Outputs[s.Down] == command.RelaisOn, "interlock")
+
+		f.sent = append(f.sent, cmd)
+		if f.silent {
+			return
+		}
+
+		pkt, err := command.NewPacket(1, 0, byte(s.Module), cmd)
+		require.NoError(t, err)
+
+		registry.Handle(pkt)
+	}
+
+	shades := shade.New(catalog, registry, send,
+		shade.Clock(func() time.Time { return f.now }),
+		shade.ReversePause(time.Second),
+		shade.AfterFunc(func(d time.Duration, fn func()) shade.Timer {
+			timer := &fakeTimer{duration: d, f: fn}
+			f.timers = append(f.timers, timer)
+
+			return timer
+		}),
+	)
+
+	return shades, f
+}
+
+func TestController(t *testing.T) {
+	t.Parallel()
+
+	shades, f := newShades(t)
+	ctx := context.Background()
+
+	c, ok := shades.Get(0, 31, 0)
+	require.True(t, ok)
+
+	assert.ErrorIs(t, c.SetPosition(ctx, 50), shade.ErrPositionUnknown)
+	assert.ErrorIs(t, c.SetPosition(ctx, 101), shade.ErrInvalidPosition)
+
+	// closing fully makes the position known
+	c.Close(ctx)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOn}, f.last())
+	assert.Equal(t, state.ShadeClosing, c.Moving())
+	assert.Equal(t, 11*time.Second, f.timers[0].duration, "travel time with overrun")
+
+	f.fire(t, 11*time.Second)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOff}, f.last())
+	assert.Equal(t, state.ShadeStopped, c.Moving())
+
+	position, known := c.Position()
+	assert.True(t, known)
+	assert.Equal(t, shade.Closed, position)
+
+	// half way up takes half the travel time up, once the pause after closing passed
+	f.now = f.now.Add(time.Second)
+	require.NoError(t, c.SetPosition(ctx, 50))
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOn, command.RelaisOff}, f.last())
+	assert.Equal(t, 10*time.Second, f.timers[1].duration)
+
+	f.now = f.now.Add(5 * time.Second)
+	position, _ = c.Position()
+	assert.Equal(t, 25.0, position)
+
+	f.fire(t, 5*time.Second)
+	position, _ = c.Position()
+	assert.Equal(t, 50.0, position)
+
+	// reversing stops first and waits for the pause
+	c.Open(ctx)
+	f.now = f.now.Add(2 * time.Second)
+	c.Close(ctx)
+	assert.True(t, f.timers[2].stopped, "pending stop of the opening is dropped")
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOff}, f.last())
+	assert.Equal(t, time.Second, f.timers[3].duration)
+
+	position, _ = c.Position()
+	assert.Equal(t, 60.0, position)
+
+	f.fire(t, time.Second)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOn}, f.last())
+	assert.Equal(t, state.ShadeClosing, c.Moving())
+
+	c.Stop(ctx)
+	assert.True(t, f.timers[4].stopped)
+	assert.Equal(t, state.ShadeStopped, c.Moving())
+}
+
+func TestReversePauseAfterStop(t *testing.T) {
+	t.Parallel()
+
+	shades, f := newShades(t)
+	ctx := context.Background()
+
+	c, ok := shades.Get(0, 31, 0)
+	require.True(t, ok)
+
+	c.Open(ctx)
+	f.now = f.now.Add(3 * time.Second)
+	c.Stop(ctx)
+
+	// reversed right after the stop, the rest of the pause is waited for
+	f.now = f.now.Add(300 * time.Millisecond)
+	c.Close(ctx)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOff}, f.last())
+	assert.Equal(t, 700*time.Millisecond, f.timers[len(f.timers)-1].duration)
+
+	f.fire(t, 700*time.Millisecond)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOn}, f.last())
+
+	// the same direction again needs no pause
+	c.Stop(ctx)
+	sent := len(f.sent)
+	c.Close(ctx)
+	assert.Len(t, f.sent, sent+1)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOn}, f.last())
+}
+
+func TestReversePauseUnacknowledged(t *testing.T) {
+	t.Parallel()
+
+	shades, f := newShades(t)
+	f.silent = true
+	ctx := context.Background()
+
+	c, ok := shades.Get(0, 31, 0)
+	require.True(t, ok)
+
+	// the relais never report, the frames sent decide
+	c.Open(ctx)
+	c.Close(ctx)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOff}, f.last())
+	assert.Equal(t, time.Second, f.timers[len(f.timers)-1].duration)
+
+	f.fire(t, time.Second)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOn}, f.last())
+
+	// a stop is a drop as well
+	f.now = f.now.Add(3 * time.Second)
+	c.Stop(ctx)
+	f.now = f.now.Add(400 * time.Millisecond)
+	c.Open(ctx)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOff, command.RelaisOff}, f.last())
+	assert.Equal(t, 600*time.Millisecond, f.timers[len(f.timers)-1].duration)
+
+	f.fire(t, 600*time.Millisecond)
+	assert.Equal(t, [2]command.RelaisAction{command.RelaisOn, command.RelaisOff}, f.last())
+	assert.Equal(t, state.ShadeStopped, c.Moving(), "nothing observed")
+}
+
+func TestSetCurrentPosition(t *testing.T) {
+	t.Parallel()
+
+	shades, f := newShades(t)
+	ctx := context.Background()
+
+	c, ok := shades.Get(0, 31, 0)
+	require.True(t, ok)
+
+	c.Close(ctx)
+	f.fire(t, 11*time.Second)
+	f.now = f.now.Add(time.Second)
+	require.NoError(t, c.SetPosition(ctx, 50))
+	f.now = f.now.Add(2 * time.Second)
+
+	// moving up through 10 %, setting that position leaves the shade moving
+	sent := len(f.sent)
+	require.NoError(t, c.SetPosition(ctx, 10))
+	assert.Len(t, f.sent, sent)
+	assert.Equal(t, state.ShadeOpening, c.Moving())
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	shades, _ := newShades(t)
+
+	relais := func(output int, action command.RelaisAction) *lcn.LcnPacket {
+		cmd := new(command.RelaisCommand)
+		cmd.Outputs[output] = action
+
+		pkt, err := command.NewPacket(1, 0, 31, cmd)
+		require.NoError(t, err)
+
+		return pkt
+	}
+
+	assert.ErrorIs(t, shades.Check(relais(1, command.RelaisOn)), shade.ErrInterlock)
+	assert.ErrorIs(t, shades.Check(relais(0, command.RelaisToggle)), shade.ErrInterlock)
+	assert.NoError(t, shades.Check(relais(2, command.RelaisOn)))
+	assert.NoError(t, shades.Check(&lcn.LcnPacket{Dst: 31, Cmd: 0x6E, Payload: []byte{0xFB, 0x00}}))
+
+	// whatever it would switch, an undecodable relais command to a module driving shades is refused
+	assert.ErrorIs(t, shades.Check(&lcn.LcnPacket{Dst: 31, Cmd: 0x13, Payload: []byte{0x04}}), shade.ErrInterlock)
+	assert.NoError(t, shades.Check(&lcn.LcnPacket{Dst: 33, Cmd: 0x13, Payload: []byte{0x04}}))
+}
@@ -0,0 +1,42 @@
+package simulator
+
+import (
+	"github.com/MyChaOS87/reverseLCN/config"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		modules []address
+		sensors []Sensor
+	}
+)
+
+// Module adds an emulated module.
+func Module(segment, module byte) Option {
+	return func(c *Config) {
+		c.modules = append(c.modules, address{segment: segment, module: module})
+	}
+}
+
+// Devices adds all modules of the devices configuration.
+func Devices(devices config.DevicesConfig) Option {
+	return func(c *Config) {
+		for _, segment := range devices.Segments {
+			for _, module := range segment.Modules {
+				Module(byte(segment.ID), byte(module.ID))(c)
+			}
+		}
+	}
+}
+
+// Sensors adds frames which are sent periodically.
+func Sensors(sensors ...Sensor) Option {
+	return func(c *Config) {
+		c.sensors = append(c.sensors, sensors...)
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{}
+}
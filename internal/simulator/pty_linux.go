@@ -0,0 +1,86 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// PTY is a pseudo terminal, the simulator uses the master side and lcn2mqtt opens the slave as serial device.
+type PTY struct {
+	*os.File // master
+
+	SlaveName string
+	slave     *os.File
+}
+
+// OpenPTY opens a pseudo terminal with its slave in raw mode.
+// The slave is kept open, so reading the master does not fail while no client is connected.
+func OpenPTY() (*PTY, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open pty master")
+	}
+
+	fail := func(err error, msg string) (*PTY, error) {
+		master.Close()
+
+		return nil, errors.Wrap(err, msg)
+	}
+
+	fd := int(master.Fd())
+
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		return fail(err, "cannot unlock pty")
+	}
+
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		return fail(err, "cannot get pty number")
+	}
+
+	slaveName := fmt.Sprintf("/dev/pts/%d", n)
+
+	slave, err := os.OpenFile(slaveName, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return fail(err, "cannot open pty slave")
+	}
+
+	if err := makeRaw(int(slave.Fd())); err != nil {
+		slave.Close()
+
+		return fail(err, "cannot set pty slave to raw mode")
+	}
+
+	return &PTY{
+		File:      master,
+		SlaveName: slaveName,
+		slave:     slave,
+	}, nil
+}
+
+// makeRaw disables echo and all line processing like cfmakeraw(3).
+func makeRaw(fd int) error {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return errors.Wrap(err, "cannot get termios")
+	}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	return errors.Wrap(unix.IoctlSetTermios(fd, unix.TCSETS, termios), "cannot set termios")
+}
+
+func (p *PTY) Close() error {
+	p.slave.Close()
+
+	return p.File.Close()
+}
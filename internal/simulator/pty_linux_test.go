@@ -0,0 +1,52 @@
+package simulator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/simulator"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+// TestPTY runs the simulator against a port opening the pseudo terminal like lcn2mqtt does.
+func TestPTY(t *testing.T) {
+	pty, err := simulator.OpenPTY()
+	if err != nil {
+		t.Skipf("no pseudo terminals available: %s", err)
+	}
+	defer pty.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go simulator.New(simulator.Module(0, 4)).Run(ctx, pty) //nolint:errcheck
+
+	port := serial.NewPort(
+		serial.PortName(pty.SlaveName),
+		serial.Deserializer(lcn.Deserialize),
+	)
+	port.Run(ctx, cancel, func(packet.Packet) {})
+
+	on := &command.RelaisCommand{}
+	on.Outputs[1] = command.RelaisOn
+
+	sent, err := command.NewPacket(1, 0, 4, on)
+	require.NoError(t, err)
+
+	buf, err := sent.Serialize()
+	require.NoError(t, err)
+
+	response, err := port.Request(ctx, buf, command.ExpectResponse(sent))
+	require.NoError(t, err)
+
+	status, err := command.Decode(response.(*lcn.LcnPacket))
+	require.NoError(t, err)
+	assert.Equal(t, &command.RelaisStatus{Outputs: [8]bool{false, true}}, status)
+}
@@ -0,0 +1,204 @@
+// Package simulator emulates LCN modules on a byte stream, e.g. a pseudo terminal lcn2mqtt opens as serial device.
+package simulator
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+const bufferSize = 1024
+
+type address struct {
+	segment byte
+	module  byte
+}
+
+// module is the emulated state of a single module, shades are driven by its relais as well.
+type module struct {
+	relais [8]bool
+}
+
+// Sensor is a frame sent periodically, e.g. a temperature reading captured from a real bus.
+type Sensor struct {
+	Packet   *lcn.LcnPacket
+	Interval time.Duration
+}
+
+type Simulator struct {
+	mutex   sync.Mutex
+	modules map[address]*module
+	sensors []Sensor
+
+	writeMutex sync.Mutex
+}
+
+// Handle applies a received packet to the addressed module and returns the packets it answers with.
+func (s *Simulator) Handle(pkt *lcn.LcnPacket) []*lcn.LcnPacket {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.modules[address{segment: pkt.Seg, module: pkt.Dst}]
+	if !ok {
+		return nil
+	}
+
+	cmd, err := command.Decode(pkt)
+	if err != nil {
+		log.Warnf("Module %d ignores %s: %s", pkt.Dst, pkt.ToNiceString(), err)
+
+		return nil
+	}
+
+	var response command.Command
+
+	// status reports go to the status report target like on the bus, query responses to the sender
+	dst := pkt.Src
+
+	switch cmd := cmd.(type) {
+	case *command.RelaisCommand:
+		m.apply(cmd)
+
+		response, dst = &command.RelaisStatus{Outputs: m.relais}, command.StatusReportTarget
+	case *command.StatusQuery:
+		if cmd.Response {
+			return nil
+		}
+
+		response = &command.StatusQuery{Response: true, Outputs: m.relais}
+	default:
+		log.Debugf("Module %d does not emulate %s", pkt.Dst, cmd)
+
+		return nil
+	}
+
+	answer, err := command.NewPacket(pkt.Dst, pkt.Seg, dst, response)
+	if err != nil {
+		log.Errorf("Module %d cannot answer %s: %s", pkt.Dst, pkt.ToNiceString(), err)
+
+		return nil
+	}
+
+	return []*lcn.LcnPacket{answer}
+}
+
+func (m *module) apply(cmd *command.RelaisCommand) {
+	for i, action := range cmd.Outputs {
+		switch action {
+		case command.RelaisOn:
+			m.relais[i] = true
+		case command.RelaisOff:
+			m.relais[i] = false
+		case command.RelaisToggle:
+			m.relais[i] = !m.relais[i]
+		case command.RelaisNoChange:
+		}
+	}
+}
+
+// Relais returns the emulated relais of a module.
+func (s *Simulator) Relais(segment, module byte) ([8]bool, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.modules[address{segment: segment, module: module}]
+	if !ok {
+		return [8]bool{}, false
+	}
+
+	return m.relais, true
+}
+
+// Run answers frames read from bus and sends the sensor frames until ctx is done or reading fails.
+func (s *Simulator) Run(ctx context.Context, bus io.ReadWriter) error {
+	for _, sensor := range s.sensors {
+		go s.runSensor(ctx, bus, sensor)
+	}
+
+	c := chunker.NewChunker(lcn.Deserialize, lcn.MIN_LCN_PACKET_LENGTH)
+	buffer := make([]byte, bufferSize)
+
+	for ctx.Err() == nil {
+		n, err := bus.Read(buffer)
+		if err != nil {
+			return errors.Wrap(err, "cannot read from bus")
+		}
+
+		c.Collect(buffer[:n], func(p packet.Packet) {
+			pkt, ok := p.(*lcn.LcnPacket)
+			if !ok {
+				return
+			}
+
+			log.Infof("rx %s", pkt.ToNiceString())
+
+			for _, answer := range s.Handle(pkt) {
+				s.send(bus, answer)
+			}
+		})
+	}
+
+	return ctx.Err()
+}
+
+func (s *Simulator) runSensor(ctx context.Context, bus io.Writer, sensor Sensor) {
+	ticker := time.NewTicker(sensor.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.send(bus, sensor.Packet)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Simulator) send(bus io.Writer, pkt *lcn.LcnPacket) {
+	buf, err := pkt.Serialize()
+	if err != nil {
+		log.Errorf("Cannot serialize %s: %s", pkt.ToNiceString(), err)
+
+		return
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	if _, err := bus.Write(buf); err != nil {
+		log.Errorf("Cannot send %s: %s", pkt.ToNiceString(), err)
+
+		return
+	}
+
+	log.Infof("tx %s", pkt.ToNiceString())
+}
+
+func New(options ...Option) *Simulator {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	s := &Simulator{
+		modules: make(map[address]*module),
+		sensors: config.sensors,
+	}
+
+	for _, a := range config.modules {
+		s.modules[a] = &module{}
+	}
+
+	return s
+}
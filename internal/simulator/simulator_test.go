@@ -0,0 +1,83 @@
+package simulator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/simulator"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+func newPacket(t *testing.T, src, seg, dst byte, cmd command.Command) *lcn.LcnPacket {
+	t.Helper()
+
+	pkt, err := command.NewPacket(src, seg, dst, cmd)
+	assert.NoError(t, err)
+
+	return pkt
+}
+
+func TestHandle(t *testing.T) {
+	s := simulator.New(simulator.Module(0, 4))
+
+	on := &command.RelaisCommand{}
+	on.Outputs[0] = command.RelaisOn
+	on.Outputs[2] = command.RelaisToggle
+
+	tests := []struct {
+		name     string
+		pkt      *lcn.LcnPacket
+		expected []*lcn.LcnPacket
+		relais   [8]bool
+	}{
+		{
+			name: "query initial state",
+			pkt:  newPacket(t, 1, 0, 4, &command.StatusQuery{}),
+			expected: []*lcn.LcnPacket{
+				newPacket(t, 4, 0, 1, &command.StatusQuery{Response: true}),
+			},
+		},
+		{
+			name: "relais command is reported",
+			pkt:  newPacket(t, 1, 0, 4, on),
+			expected: []*lcn.LcnPacket{
+				newPacket(t, 4, 0, command.StatusReportTarget, &command.RelaisStatus{Outputs: [8]bool{true, false, true}}),
+			},
+			relais: [8]bool{true, false, true},
+		},
+		{
+			name: "query changed state",
+			pkt:  newPacket(t, 7, 0, 4, &command.StatusQuery{}),
+			expected: []*lcn.LcnPacket{
+				newPacket(t, 4, 0, 7, &command.StatusQuery{Response: true, Outputs: [8]bool{true, false, true}}),
+			},
+			relais: [8]bool{true, false, true},
+		},
+		{
+			name:   "other module",
+			pkt:    newPacket(t, 1, 0, 5, on),
+			relais: [8]bool{true, false, true},
+		},
+		{
+			name:   "other segment",
+			pkt:    newPacket(t, 1, 3, 4, on),
+			relais: [8]bool{true, false, true},
+		},
+		{
+			name:   "responses are ignored",
+			pkt:    newPacket(t, 1, 0, 4, &command.StatusQuery{Response: true}),
+			relais: [8]bool{true, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, s.Handle(tt.pkt))
+
+			relais, ok := s.Relais(0, 4)
+			assert.True(t, ok)
+			assert.Equal(t, tt.relais, relais)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		catalog *monitor.Catalog
+		clock   func() time.Time
+	}
+)
+
+// Catalog sets the configured modules and outputs, they are known to the registry before any frame was seen.
+func Catalog(catalog *monitor.Catalog) Option {
+	return func(c *Config) {
+		c.catalog = catalog
+	}
+}
+
+// Clock replaces time.Now for the timestamps of changes.
+func Clock(clock func() time.Time) Option {
+	return func(c *Config) {
+		c.clock = clock
+	}
+}
+
+func newDefaultConfig() *Config {
+	catalog, _ := monitor.NewCatalog(config.DevicesConfig{})
+
+	return &Config{
+		catalog: catalog,
+		clock:   time.Now,
+	}
+}
@@ -0,0 +1,384 @@
+// Package state keeps the state of module outputs as far as it can be derived from bus traffic.
+package state
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+const outputs = 8
+
+type ShadeState int
+
+const (
+	ShadeStopped ShadeState = iota
+	ShadeOpening
+	ShadeClosing
+)
+
+func (s ShadeState) String() string {
+	switch s {
+	case ShadeOpening:
+		return "opening"
+	case ShadeClosing:
+		return "closing"
+	case ShadeStopped:
+	}
+
+	return "stopped"
+}
+
+// Key identifies a zero based output of a module.
+type Key struct {
+	Segment int
+	Module  int
+	Output  int
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%d:%d/%d", k.Segment, k.Module, k.Output+1)
+}
+
+// Output is the state of a relais output, outputs configured as dimmer are analog outputs and never known.
+type Output struct {
+	Key
+	Name    string
+	Type    string // configured type, empty if not configured
+	Known   bool   // On is only meaningful once known
+	On      bool
+	Changed time.Time // last change of On or Known
+	Updated time.Time // last frame setting or reporting the output
+}
+
+// Event is a change of an output, it became known or switched.
+type Event struct {
+	Output   Output
+	Previous Output
+}
+
+type moduleKey struct {
+	segment int
+	module  int
+}
+
+// Registry holds the outputs of all modules, configured ones from the start and others once seen.
+type Registry struct {
+	catalog *monitor.Catalog
+	clock   func() time.Time
+
+	mutex       sync.Mutex
+	modules     map[moduleKey]*[outputs]Output
+	subscribers []func([]Event)
+	pending     [][]Event // changes not yet delivered to the subscribers, oldest first
+	delivering  bool      // a Handle is delivering pending
+}
+
+func NewRegistry(options ...Option) *Registry {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	r := &Registry{
+		catalog: config.catalog,
+		clock:   config.clock,
+		modules: make(map[moduleKey]*[outputs]Output),
+	}
+
+	devices := config.catalog.Devices()
+
+	for _, light := range devices.Lights {
+		r.module(light.Segment, light.Module)[light.Output].Type = monitor.OutputTypeRelay
+	}
+
+	for _, dimmer := range devices.Dimmers {
+		r.module(dimmer.Segment, dimmer.Module)[dimmer.Output].Type = monitor.OutputTypeDimmer
+	}
+
+	for _, shade := range devices.Shades {
+		r.module(shade.Segment, shade.Module)[shade.Output].Type = monitor.OutputTypeShade
+		r.module(shade.Segment, shade.Module)[shade.Down].Type = monitor.OutputTypeShade
+	}
+
+	return r
+}
+
+// Subscribe registers a callback for the changes caused by a frame, it is called after the registry was updated.
+// Changes are delivered one frame at a time in the order they were applied, possibly by a concurrent Handle.
+func (r *Registry) Subscribe(callback func([]Event)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.subscribers = append(r.subscribers, callback)
+}
+
+// Handle updates the outputs from a frame seen on the bus and returns the changes.
+func (r *Registry) Handle(pkt *lcn.LcnPacket) []Event {
+	if pkt.Invalid {
+		return nil
+	}
+
+	cmd, err := command.Decode(pkt)
+	if err != nil {
+		return nil
+	}
+
+	key, ok := r.target(pkt, cmd)
+	if !ok {
+		return nil
+	}
+
+	now := r.clock()
+
+	r.mutex.Lock()
+
+	var events []Event
+
+	m := r.module(key.segment, key.module)
+
+	switch c := cmd.(type) {
+	case *command.RelaisCommand:
+		events = applyCommand(m, c, now)
+	case *command.RelaisStatus:
+		events = applyStatus(m, c.Outputs, now)
+	case *command.StatusQuery:
+		events = applyStatus(m, c.Outputs, now)
+	}
+
+	if len(events) > 0 {
+		r.pending = append(r.pending, events)
+	}
+
+	r.deliver()
+
+	return events
+}
+
+// deliver passes the pending changes to the subscribers unless another Handle already does, the mutex must be
+// held and is released. Callbacks run without the mutex, so they may query or update the registry.
+func (r *Registry) deliver() {
+	if r.delivering {
+		r.mutex.Unlock()
+
+		return
+	}
+
+	r.delivering = true
+
+	for len(r.pending) > 0 {
+		events := r.pending[0]
+		r.pending = r.pending[1:]
+		subscribers := slices.Clone(r.subscribers)
+
+		r.mutex.Unlock()
+
+		for _, callback := range subscribers {
+			callback(events)
+		}
+
+		r.mutex.Lock()
+	}
+
+	r.delivering = false
+
+	r.mutex.Unlock()
+}
+
+// Affected returns the outputs of the module a frame switches or reports.
+func (r *Registry) Affected(pkt *lcn.LcnPacket) ([outputs]Output, bool) {
+	cmd, err := command.Decode(pkt)
+	if err != nil {
+		return [outputs]Output{}, false
+	}
+
+	key, ok := r.target(pkt, cmd)
+	if !ok {
+		return [outputs]Output{}, false
+	}
+
+	return r.Module(key.segment, key.module)
+}
+
+// target returns the module whose outputs a command switches or reports, status reports count only when sent to
+// command.StatusReportTarget as in the monitor.
+func (r *Registry) target(pkt *lcn.LcnPacket, cmd command.Command) (moduleKey, bool) {
+	switch c := cmd.(type) {
+	case *command.RelaisCommand:
+		return moduleKey{segment: r.segment(int(pkt.Seg), int(pkt.Dst)), module: int(pkt.Dst)}, true
+	case *command.RelaisStatus:
+		if pkt.Dst == command.StatusReportTarget {
+			return moduleKey{segment: r.catalog.Segment(int(pkt.Src)), module: int(pkt.Src)}, true
+		}
+	case *command.StatusQuery:
+		if c.Response {
+			return moduleKey{segment: r.catalog.Segment(int(pkt.Src)), module: int(pkt.Src)}, true
+		}
+	}
+
+	return moduleKey{}, false
+}
+
+// segment resolves segment 0, the own segment, to the configured segment of the module.
+func (r *Registry) segment(segment, module int) int {
+	if segment == 0 {
+		return r.catalog.Segment(module)
+	}
+
+	return segment
+}
+
+// applyCommand applies a relais command, outputs configured as dimmer are analog outputs and not affected.
+func applyCommand(m *[outputs]Output, cmd *command.RelaisCommand, now time.Time) []Event {
+	next := *m
+
+	for i, action := range cmd.Outputs {
+		if next[i].Type == monitor.OutputTypeDimmer {
+			continue
+		}
+
+		switch action {
+		case command.RelaisOn:
+			next[i].Known, next[i].On, next[i].Updated = true, true, now
+		case command.RelaisOff:
+			next[i].Known, next[i].On, next[i].Updated = true, false, now
+		case command.RelaisToggle:
+			if next[i].Known {
+				next[i].On, next[i].Updated = !next[i].On, now
+			}
+		case command.RelaisNoChange:
+		}
+	}
+
+	return replace(m, &next, now)
+}
+
+func applyStatus(m *[outputs]Output, reported [outputs]bool, now time.Time) []Event {
+	next := *m
+
+	for i, on := range reported {
+		if next[i].Type != monitor.OutputTypeDimmer {
+			next[i].Known, next[i].On, next[i].Updated = true, on, now
+		}
+	}
+
+	return replace(m, &next, now)
+}
+
+// replace stores next and returns the outputs which switched or became known.
+func replace(m, next *[outputs]Output, now time.Time) []Event {
+	var events []Event
+
+	for i := range next {
+		if next[i].Known && (!m[i].Known || m[i].On != next[i].On) {
+			next[i].Changed = now
+			events = append(events, Event{Output: next[i], Previous: m[i]})
+		}
+	}
+
+	*m = *next
+
+	return events
+}
+
+// module returns the outputs of a module, creating them if unknown. The mutex must be held.
+func (r *Registry) module(segment, module int) *[outputs]Output {
+	key := moduleKey{segment: segment, module: module}
+
+	m, ok := r.modules[key]
+	if !ok {
+		m = new([outputs]Output)
+
+		for i := range m {
+			m[i].Key = Key{Segment: segment, Module: module, Output: i}
+			m[i].Name = r.catalog.OutputName(segment, module, i)
+		}
+
+		r.modules[key] = m
+	}
+
+	return m
+}
+
+// Output returns the state of a zero based output, false if it is not known yet.
+func (r *Registry) Output(segment, module, output int) (Output, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if output < 0 || output >= outputs {
+		return Output{}, false
+	}
+
+	m, ok := r.modules[moduleKey{segment: segment, module: module}]
+	if !ok {
+		return Output{}, false
+	}
+
+	return m[output], m[output].Known
+}
+
+// Module returns all outputs of a module, false if neither configured nor seen.
+func (r *Registry) Module(segment, module int) ([outputs]Output, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m, ok := r.modules[moduleKey{segment: segment, module: module}]
+	if !ok {
+		return [outputs]Output{}, false
+	}
+
+	return *m, true
+}
+
+// Outputs returns all known or configured outputs, sorted by segment, module and output.
+func (r *Registry) Outputs() []Output {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result []Output
+
+	for _, m := range r.modules {
+		for _, o := range m {
+			if o.Known || o.Type != "" {
+				result = append(result, o)
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b Output) int {
+		if a.Segment != b.Segment {
+			return a.Segment - b.Segment
+		}
+
+		if a.Module != b.Module {
+			return a.Module - b.Module
+		}
+
+		return a.Output - b.Output
+	})
+
+	return result
+}
+
+// Shade returns whether a shade is moving, derived from its relais.
+func (r *Registry) Shade(shade monitor.Shade) ShadeState {
+	up, upKnown := r.Output(shade.Segment, shade.Module, shade.Output)
+	down, downKnown := r.Output(shade.Segment, shade.Module, shade.Down)
+
+	opening, closing := upKnown && up.On, downKnown && down.On
+
+	switch {
+	case opening && !closing:
+		return ShadeOpening
+	case closing && !opening:
+		return ShadeClosing
+	default:
+		return ShadeStopped
+	}
+}
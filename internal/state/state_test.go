@@ -0,0 +1,182 @@
+package state_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/config"
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{{
+			ID: 5,
+			Modules: []config.ModuleConfig{
+				{ID: 31, Outputs: []config.OutputConfig{{ID: 1, Type: "shade", Name: "Shade", Down: 2}}},
+				{ID: 33, Outputs: []config.OutputConfig{{ID: 1, Type: "relay", Name: "Light"}}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2023, 3, 5, 18, 21, 7, 0, time.UTC)
+	r := state.NewRegistry(state.Catalog(catalog), state.Clock(func() time.Time { return now }))
+
+	var received [][]state.Event
+
+	r.Subscribe(func(events []state.Event) {
+		received = append(received, events)
+	})
+
+	// configured outputs are listed before anything was seen
+	outputs := r.Outputs()
+	require.Len(t, outputs, 3)
+	assert.Equal(t, state.Key{Segment: 5, Module: 31, Output: 0}, outputs[0].Key)
+	assert.Equal(t, "Light", outputs[2].Name)
+	assert.False(t, outputs[2].Known)
+
+	_, ok := r.Output(5, 33, 0)
+	assert.False(t, ok)
+
+	// a status report is attributed to the configured segment of its source
+	events := r.Handle(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}})
+	assert.Len(t, events, 8)
+	assert.Equal(t, [][]state.Event{events}, received)
+
+	light, ok := r.Output(5, 33, 0)
+	require.True(t, ok)
+	assert.True(t, light.On)
+	assert.Equal(t, "Light", light.Name)
+	assert.Equal(t, now, light.Changed)
+
+	// repeated reports change nothing
+	now = now.Add(time.Second)
+	assert.Empty(t, r.Handle(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}}))
+	assert.Len(t, received, 1)
+
+	light, _ = r.Output(5, 33, 0)
+	assert.Equal(t, now.Add(-time.Second), light.Changed)
+	assert.Equal(t, now, light.Updated)
+
+	// a command to the own segment switches the output
+	events = r.Handle(&lcn.LcnPacket{Src: 11, Seg: 0, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x01}})
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Previous.On)
+	assert.False(t, events[0].Output.On)
+
+	// toggling unknown outputs leaves them untouched
+	assert.Empty(t, r.Handle(&lcn.LcnPacket{Src: 11, Seg: 5, Dst: 31, Cmd: 0x13, Payload: []byte{0x00, 0x03}}))
+
+	toggled, ok := r.Module(5, 31)
+	require.True(t, ok)
+	assert.False(t, toggled[0].On)
+	assert.False(t, toggled[0].Known)
+
+	// reports to other targets than the status report target are not trusted, as in the monitor
+	assert.Empty(t, r.Handle(&lcn.LcnPacket{Src: 31, Dst: 1, Cmd: 0x68, Payload: []byte{0x30, 0x03}}))
+
+	_, ok = r.Affected(&lcn.LcnPacket{Src: 31, Dst: 1, Cmd: 0x68, Payload: []byte{0x30, 0x03}})
+	assert.False(t, ok)
+
+	// frames with an invalid checksum are not trusted
+	assert.Empty(t, r.Handle(&lcn.LcnPacket{Src: 11, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x02}, Invalid: true}))
+
+	shade := catalog.Devices().Shades[0]
+	assert.Equal(t, state.ShadeStopped, r.Shade(shade))
+
+	events = r.Handle(&lcn.LcnPacket{Src: 11, Seg: 5, Dst: 31, Cmd: 0x13, Payload: []byte{0x03, 0x01}})
+	assert.Len(t, events, 2)
+	assert.Equal(t, state.ShadeClosing, r.Shade(shade))
+	assert.Equal(t, "closing", r.Shade(shade).String())
+
+	module, ok := r.Module(5, 31)
+	require.True(t, ok)
+	assert.False(t, module[0].On)
+	assert.True(t, module[1].On)
+	assert.False(t, module[2].Known)
+
+	affected, ok := r.Affected(&lcn.LcnPacket{Src: 31, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x02}})
+	require.True(t, ok)
+	assert.Equal(t, module, affected)
+
+	_, ok = r.Affected(&lcn.LcnPacket{Src: 31, Dst: 4, Cmd: 0x22, Payload: []byte{0x01}})
+	assert.False(t, ok)
+}
+
+func TestDimmerNotTracked(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := monitor.NewCatalog(config.DevicesConfig{
+		Segments: []config.SegmentConfig{{
+			Modules: []config.ModuleConfig{
+				{ID: 35, Outputs: []config.OutputConfig{{ID: 2, Type: "dimmer", Name: "Dimmer"}}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	r := state.NewRegistry(state.Catalog(catalog))
+
+	// the analog output is no relais, reports and relais commands leave it unknown
+	assert.Len(t, r.Handle(&lcn.LcnPacket{Src: 35, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x02}}), 7)
+	assert.Empty(t, r.Handle(&lcn.LcnPacket{Src: 11, Dst: 35, Cmd: 0x13, Payload: []byte{0x00, 0x02}}))
+
+	_, ok := r.Output(0, 35, 1)
+	assert.False(t, ok)
+}
+
+func TestEventsInOrder(t *testing.T) {
+	t.Parallel()
+
+	r := state.NewRegistry()
+
+	var (
+		mutex     sync.Mutex
+		delivered []state.Event
+	)
+
+	r.Subscribe(func(events []state.Event) {
+		// a slow subscriber lets concurrent frames overtake each other
+		time.Sleep(100 * time.Microsecond)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		delivered = append(delivered, events...)
+	})
+
+	var wg sync.WaitGroup
+
+	for i := range 100 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// relay 1 of module 33 alternates between on and off
+			r.Handle(&lcn.LcnPacket{Src: 11, Dst: 33, Cmd: 0x13, Payload: []byte{0x01, byte(i % 2)}})
+		}()
+	}
+
+	wg.Wait()
+
+	require.NotEmpty(t, delivered)
+
+	// every event starts where the one before ended
+	for i := 1; i < len(delivered); i++ {
+		assert.Equal(t, delivered[i-1].Output.On, delivered[i].Previous.On, "event %d", i)
+		assert.Equal(t, delivered[i-1].Output.Changed, delivered[i].Previous.Changed, "event %d", i)
+	}
+
+	last, _ := r.Output(0, 33, 0)
+	assert.Equal(t, last.Changed, delivered[len(delivered)-1].Output.Changed)
+}
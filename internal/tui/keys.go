@@ -0,0 +1,94 @@
+package tui
+
+// Key is a single key press, either a rune or one of the special keys.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+)
+
+const (
+	escape    = 0x1b
+	delete    = 0x7f
+	backspace = 0x08
+)
+
+//nolint:gochecknoglobals
+var escapeSequences = map[string]KeyCode{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+}
+
+// ParseKeys splits terminal input into keys, unknown escape sequences are dropped.
+func ParseKeys(buf []byte) []Key {
+	var keys []Key
+
+	runes := []rune(string(buf))
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == escape:
+			code, length := parseEscape(runes[i+1:])
+			if code != KeyRune {
+				keys = append(keys, Key{Code: code})
+			}
+
+			i += length
+		case r == '\r' || r == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case r == delete || r == backspace:
+			keys = append(keys, Key{Code: KeyBackspace})
+		default:
+			keys = append(keys, Key{Code: KeyRune, Rune: r})
+		}
+	}
+
+	return keys
+}
+
+// parseEscape returns the key of the sequence following an escape and the number of runes it takes,
+// KeyRune for unknown sequences.
+func parseEscape(runes []rune) (KeyCode, int) {
+	if len(runes) == 0 || (runes[0] != '[' && runes[0] != 'O') {
+		return KeyEscape, 0
+	}
+
+	// sequences end with a letter or ~
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		if r == '~' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			code, ok := escapeSequences[string(runes[:i+1])]
+			if !ok {
+				return KeyRune, i + 1
+			}
+
+			return code, i + 1
+		}
+	}
+
+	return KeyRune, len(runes)
+}
@@ -0,0 +1,274 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+)
+
+// ANSI styles, highlight only resets what it sets so it can be nested in the selection.
+const (
+	styleReset     = "\x1b[0m"
+	styleSelected  = "\x1b[7m"
+	styleHeader    = "\x1b[1m"
+	styleHighlight = "\x1b[1;33m"
+	styleUnlight   = "\x1b[22;39m"
+
+	detailHeight = 16 // separator, raw, decoded, outputs, five rows of bytes, the stream and three rows each of bit changes and samples
+	headerLines  = 2  // status and column titles
+	footerLines  = 1
+)
+
+// Column is a column the table can be sorted by.
+type Column int
+
+const (
+	ColumnLastSeen Column = iota
+	ColumnCount
+	ColumnSrc
+	ColumnSeg
+	ColumnDst
+	ColumnCommand
+	columns
+)
+
+//nolint:gochecknoglobals
+var columnNames = [columns]string{"last seen", "count", "source", "segment", "target", "command"}
+
+func (c Column) String() string {
+	return columnNames[c]
+}
+
+// Model is the state of the monitor screen, independent of the terminal.
+type Model struct {
+	entries []monitor.Entry // all entries
+	view    []monitor.Entry // filtered and sorted
+
+	sort    Column
+	reverse bool
+
+	filter        string
+	editingFilter bool
+
+	selected string // key of the selected entry, kept across updates
+	cursor   int
+	offset   int
+	detail   bool
+
+	diff    monitor.Diff // of the stream of the selected entry
+	events  []monitor.Event
+	outputs []state.Output // of the module switched or reported by the selected entry
+}
+
+// Action is what the monitor has to do after a key press.
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionQuit
+	ActionMark // mark a wall-clock event
+)
+
+func NewModel() *Model {
+	return &Model{
+		sort: ColumnLastSeen,
+	}
+}
+
+func key(e *monitor.Entry) string {
+	return e.Packet.ToString()
+}
+
+// SetEntries replaces the displayed entries, keeping the selection.
+func (m *Model) SetEntries(entries []monitor.Entry) {
+	m.entries = entries
+	m.update()
+}
+
+func (m *Model) update() {
+	m.view = m.view[:0]
+
+	filter := strings.ToLower(m.filter)
+
+	for _, e := range m.entries {
+		if filter == "" || m.matches(&e, filter) {
+			m.view = append(m.view, e)
+		}
+	}
+
+	slices.SortStableFunc(m.view, m.compare)
+
+	m.cursor = 0
+
+	for i := range m.view {
+		if key(&m.view[i]) == m.selected {
+			m.cursor = i
+
+			break
+		}
+	}
+
+	m.selectCursor()
+}
+
+// matches filters by module and command.
+func (m *Model) matches(e *monitor.Entry, filter string) bool {
+	for _, field := range []string{e.Src, e.Dst, e.Command, fmt.Sprintf("%d", e.Packet.Src), fmt.Sprintf("%d", e.Packet.Dst)} {
+		if strings.Contains(strings.ToLower(field), filter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Model) compare(a, b monitor.Entry) int {
+	var result int
+
+	switch m.sort {
+	case ColumnLastSeen:
+		result = -a.LastSeen.Compare(b.LastSeen)
+	case ColumnCount:
+		result = b.Count - a.Count
+	case ColumnSrc:
+		result = int(a.Packet.Src) - int(b.Packet.Src)
+	case ColumnSeg:
+		result = int(a.Packet.Seg) - int(b.Packet.Seg)
+	case ColumnDst:
+		result = int(a.Packet.Dst) - int(b.Packet.Dst)
+	case ColumnCommand:
+		result = int(a.Packet.Cmd) - int(b.Packet.Cmd)
+	case columns:
+	}
+
+	if m.reverse {
+		return -result
+	}
+
+	return result
+}
+
+func (m *Model) selectCursor() {
+	m.cursor = max(0, min(m.cursor, len(m.view)-1))
+
+	if len(m.view) > 0 {
+		m.selected = key(&m.view[m.cursor])
+	}
+}
+
+// Selected returns the selected entry.
+func (m *Model) Selected() (monitor.Entry, bool) {
+	if len(m.view) == 0 {
+		return monitor.Entry{}, false
+	}
+
+	return m.view[m.cursor], true
+}
+
+// SetDiff sets the changes of the stream of the selected entry shown in the detail pane.
+func (m *Model) SetDiff(diff monitor.Diff) {
+	m.diff = diff
+}
+
+// SetOutputs sets the outputs of the module switched or reported by the selected entry, nil if there is none.
+func (m *Model) SetOutputs(outputs []state.Output) {
+	m.outputs = outputs
+}
+
+// SetEvents sets the marked events.
+func (m *Model) SetEvents(events []monitor.Event) {
+	m.events = events
+}
+
+// Detail reports whether the detail pane is shown.
+func (m *Model) Detail() bool {
+	return m.detail
+}
+
+// HandleKey applies a key press and returns what the monitor has to do.
+func (m *Model) HandleKey(k Key, pageSize int) Action {
+	if m.editingFilter {
+		m.editFilter(k)
+
+		return ActionNone
+	}
+
+	switch k.Code {
+	case KeyUp:
+		m.cursor--
+	case KeyDown:
+		m.cursor++
+	case KeyPageUp:
+		m.cursor -= pageSize
+	case KeyPageDown:
+		m.cursor += pageSize
+	case KeyHome:
+		m.cursor = 0
+	case KeyEnd:
+		m.cursor = len(m.view) - 1
+	case KeyEnter:
+		m.detail = !m.detail
+	case KeyEscape:
+		m.detail = false
+		m.filter = ""
+		m.update()
+	case KeyRune:
+		switch k.Rune {
+		case 'q':
+			return ActionQuit
+		case 'm':
+			return ActionMark
+		case 's':
+			m.sort = (m.sort + 1) % columns
+			m.update()
+		case 'r':
+			m.reverse = !m.reverse
+			m.update()
+		case '/':
+			m.editingFilter = true
+		case 'k':
+			m.cursor--
+		case 'j':
+			m.cursor++
+		}
+	case KeyBackspace:
+	}
+
+	m.selectCursor()
+
+	return ActionNone
+}
+
+func (m *Model) editFilter(k Key) {
+	switch k.Code {
+	case KeyEnter:
+		m.editingFilter = false
+	case KeyEscape:
+		m.editingFilter = false
+		m.filter = ""
+	case KeyBackspace:
+		if m.filter != "" {
+			_, size := utf8.DecodeLastRuneInString(m.filter)
+			m.filter = m.filter[:len(m.filter)-size]
+		}
+	case KeyRune:
+		m.filter += string(k.Rune)
+	case KeyUp, KeyDown, KeyPageUp, KeyPageDown, KeyHome, KeyEnd:
+	}
+
+	m.update()
+}
+
+// TableHeight returns the number of table rows shown on a screen of the given height.
+func (m *Model) TableHeight(height int) int {
+	rows := height - headerLines - footerLines
+	if m.detail {
+		rows -= detailHeight
+	}
+
+	return max(rows, 1)
+}
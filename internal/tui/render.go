@@ -0,0 +1,357 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+)
+
+const (
+	timeFormat   = "15:04:05"
+	timeWidth    = 8
+	countWidth   = 6
+	moduleWidth  = 22
+	segWidth     = 3
+	commandWidth = 18
+	hexWidth     = 28 // 14 payload bytes
+	bitsPerRow   = 4
+	byteRows     = 5 // 20 bytes at most
+	changeRows   = 3
+	sampleRows   = 3
+)
+
+//nolint:gochecknoglobals
+var byteNames = []string{"Src", "Info", "Chk", "Seg", "Dst", "Cmd"}
+
+// Render returns the screen lines for a terminal of the given size, lines may contain ANSI styles.
+func (m *Model) Render(width, height int) []string {
+	lines := make([]string, 0, height)
+
+	lines = append(lines, fit(m.renderStatus(), width))
+	lines = append(lines, styleHeader+fit(fmt.Sprintf("%-*s %*s %-*s %*s %-*s %-*s %-*s %s",
+		timeWidth, "seen", countWidth, "#", moduleWidth, "source", segWidth, "seg", moduleWidth, "target",
+		commandWidth, "command", hexWidth, "payload", "decoded"), width)+styleReset)
+
+	rows := m.TableHeight(height)
+	m.scroll(rows)
+
+	for i := m.offset; i < m.offset+rows; i++ {
+		if i >= len(m.view) {
+			lines = append(lines, "")
+
+			continue
+		}
+
+		line := fit(renderEntry(&m.view[i]), width)
+		if i == m.cursor {
+			line = styleSelected + line + strings.Repeat(" ", max(0, width-visibleLength(line))) + styleReset
+		}
+
+		lines = append(lines, line)
+	}
+
+	if m.detail {
+		detail := m.renderDetail()
+		for i := 0; i < detailHeight; i++ {
+			if i < len(detail) {
+				lines = append(lines, fit(detail[i], width))
+			} else {
+				lines = append(lines, "")
+			}
+		}
+	}
+
+	lines = append(lines, fit(m.renderHelp(), width))
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	return lines
+}
+
+// scroll keeps the cursor within the visible rows.
+func (m *Model) scroll(rows int) {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+
+	m.offset = max(0, min(m.offset, len(m.view)-rows))
+}
+
+func (m *Model) renderStatus() string {
+	direction := "descending"
+	if m.reverse {
+		direction = "ascending"
+	}
+
+	status := fmt.Sprintf("lcnMonitor  %d/%d frames  sorted by %s %s", len(m.view), len(m.entries), m.sort, direction)
+
+	if len(m.events) > 0 {
+		last := m.events[len(m.events)-1]
+		status += fmt.Sprintf("  %s at %s", last.Name, last.Time.Local().Format(timeFormat))
+	}
+
+	switch {
+	case m.editingFilter:
+		status += "  filter: " + m.filter + "_"
+	case m.filter != "":
+		status += "  filter: " + m.filter
+	}
+
+	return status
+}
+
+func (m *Model) renderHelp() string {
+	if m.editingFilter {
+		return "type to filter by module or command  enter: done  esc: clear"
+	}
+
+	return "↑/↓ select  enter: details  s: sort  r: reverse  /: filter  esc: clear  m: mark event  q: quit"
+}
+
+func renderEntry(e *monitor.Entry) string {
+	hex := highlight(e.Packet.Payload, e.Changed, "")
+
+	return fmt.Sprintf("%-*s %*d %-*s %*d %-*s %-*s %s%s %s",
+		timeWidth, e.LastSeen.Local().Format(timeFormat),
+		countWidth, e.Count,
+		moduleWidth, truncate(e.Src, moduleWidth),
+		segWidth, e.Packet.Seg,
+		moduleWidth, truncate(e.Dst, moduleWidth),
+		commandWidth, truncate(e.Command, commandWidth),
+		hex, strings.Repeat(" ", max(0, hexWidth-2*len(e.Packet.Payload))),
+		e.Payload)
+}
+
+func (m *Model) renderDetail() []string {
+	e, ok := m.Selected()
+	if !ok {
+		return []string{strings.Repeat("─", timeWidth), "no frame selected"}
+	}
+
+	raw, err := e.Packet.Serialize()
+	if err != nil {
+		return []string{strings.Repeat("─", timeWidth), fmt.Sprintf("cannot serialize: %s", err)}
+	}
+
+	changed := append(make([]bool, len(byteNames)), e.Changed...)
+
+	lines := []string{
+		strings.Repeat("─", timeWidth) + fmt.Sprintf(" %s -> %s (segment %d) %s  INFO %08b%s",
+			e.Src, e.Dst, e.Packet.Seg, e.Command, e.Packet.Info, infoFlags(e.Packet.InfoFields())),
+		"raw:     " + highlight(raw, changed, " "),
+		"decoded: " + e.Payload,
+		m.renderOutputs(),
+	}
+
+	var row []string
+
+	for i, b := range raw {
+		name := fmt.Sprintf("P%d", i-len(byteNames))
+		if i < len(byteNames) {
+			name = byteNames[i]
+		}
+
+		cell := fmt.Sprintf("%-4s %02x %08b", name, b, b)
+		if i < len(changed) && changed[i] {
+			cell = styleHighlight + cell + styleUnlight
+		}
+
+		row = append(row, cell)
+
+		if len(row) == bitsPerRow || i == len(raw)-1 {
+			lines = append(lines, strings.Join(row, "   "))
+			row = row[:0]
+		}
+	}
+
+	for len(lines) < 4+byteRows {
+		lines = append(lines, "")
+	}
+
+	return append(lines, m.renderDiff()...)
+}
+
+// renderOutputs shows the state of the outputs switched or reported by the selected entry.
+func (m *Model) renderOutputs() string {
+	if len(m.outputs) == 0 {
+		return "outputs: -"
+	}
+
+	parts := make([]string, len(m.outputs))
+
+	for i, o := range m.outputs {
+		switch {
+		case !o.Known:
+			parts[i] = fmt.Sprintf("%d:?", o.Output+1)
+		case o.On:
+			parts[i] = fmt.Sprintf("%d:%son%s", o.Output+1, styleHighlight, styleUnlight)
+		default:
+			parts[i] = fmt.Sprintf("%d:off", o.Output+1)
+		}
+	}
+
+	return fmt.Sprintf("outputs: %d:%d  %s", m.outputs[0].Segment, m.outputs[0].Module, strings.Join(parts, " "))
+}
+
+// renderDiff shows which payload bits of the selected stream changed and the latest frames with
+// the events preceding them.
+func (m *Model) renderDiff() []string {
+	d := &m.diff
+
+	lines := []string{fmt.Sprintf("stream %s: %d frames, %d changed", d.Stream, d.Frames, d.Changes)}
+
+	changes := make([]string, 0, changeRows)
+
+	for i, count := range d.ByteChanges {
+		if count == 0 || len(changes) == changeRows {
+			continue
+		}
+
+		bits := make([]string, 8)
+		for bit := range bits {
+			bits[bit] = fmt.Sprintf("%3d", d.BitChanges[i][7-bit])
+		}
+
+		changes = append(changes, fmt.Sprintf("P%-3d %4dx  bits 7..0:%s", i, count, strings.Join(bits, "")))
+	}
+
+	for len(changes) < changeRows {
+		changes = append(changes, "")
+	}
+
+	lines = append(lines, changes...)
+
+	samples := d.Samples[max(0, len(d.Samples)-sampleRows):]
+	for i := len(samples) - 1; i >= 0; i-- {
+		sample := &samples[i]
+		line := sample.Time.Local().Format(timeFormat+".000") + "  " +
+			highlight(sample.Payload, changedBytes(sample.Flipped), " ")
+
+		if sample.Event != nil {
+			line += fmt.Sprintf("  %s after %s", sample.Time.Sub(sample.Event.Time).Round(time.Millisecond), sample.Event.Name)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func changedBytes(flipped []byte) []bool {
+	changed := make([]bool, len(flipped))
+	for i, b := range flipped {
+		changed[i] = b != 0
+	}
+
+	return changed
+}
+
+// infoFlags lists the guessed flags of the INFO byte which are set.
+func infoFlags(f lcn.InfoFields) string {
+	var flags string
+
+	if f.AckRequested {
+		flags += " ack?"
+	}
+
+	if f.FromModule {
+		flags += " from module?"
+	}
+
+	if f.Priority != 0 {
+		flags += fmt.Sprintf(" priority %d?", f.Priority)
+	}
+
+	if f.Routed != 0 {
+		flags += fmt.Sprintf(" routed %d?", f.Routed)
+	}
+
+	return flags
+}
+
+// highlight renders bytes as hex, marking the changed ones.
+func highlight(buf []byte, changed []bool, separator string) string {
+	parts := make([]string, len(buf))
+
+	for i, b := range buf {
+		parts[i] = fmt.Sprintf("%02x", b)
+		if i < len(changed) && changed[i] {
+			parts[i] = styleHighlight + parts[i] + styleUnlight
+		}
+	}
+
+	return strings.Join(parts, separator)
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+
+	return string(runes[:width-1]) + "…"
+}
+
+// fit cuts a line to the given number of visible runes, keeping ANSI styles intact.
+func fit(line string, width int) string {
+	var (
+		b       strings.Builder
+		visible int
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = !isFinal(r)
+		case r == escape:
+			escaped = true
+		case visible == width:
+			continue
+		default:
+			visible++
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// isFinal reports whether r ends an escape sequence.
+func isFinal(r rune) bool {
+	return r >= '@' && r <= '~' && r != '['
+}
+
+func visibleLength(line string) int {
+	return len([]rune(stripStyles(line)))
+}
+
+func stripStyles(line string) string {
+	var (
+		b       strings.Builder
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = !isFinal(r)
+		case r == escape:
+			escaped = true
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
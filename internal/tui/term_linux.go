@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+type terminal struct {
+	fd       int
+	original unix.Termios
+}
+
+// openTerminal switches the terminal to unbuffered input without echo, reads return every 100ms.
+// Signals are kept, so ctrl-c still interrupts.
+func openTerminal(fd int) (*terminal, error) {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, errors.Wrap(err, "not a terminal")
+	}
+
+	t := &terminal{fd: fd, original: *termios}
+
+	termios.Lflag &^= unix.ECHO | unix.ICANON
+	termios.Cc[unix.VMIN] = 0
+	termios.Cc[unix.VTIME] = 1
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, termios); err != nil {
+		return nil, errors.Wrap(err, "cannot set terminal mode")
+	}
+
+	return t, nil
+}
+
+// size returns the columns and rows of the terminal.
+func (t *terminal) size() (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(t.fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "cannot get terminal size")
+	}
+
+	return int(ws.Col), int(ws.Row), nil
+}
+
+func (t *terminal) restore() error {
+	return errors.Wrap(unix.IoctlSetTermios(t.fd, unix.TCSETS, &t.original), "cannot restore terminal mode")
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package tui
+
+import "github.com/pkg/errors"
+
+var errUnsupported = errors.New("terminal UI is only supported on Linux")
+
+type terminal struct{}
+
+func openTerminal(int) (*terminal, error) {
+	return nil, errUnsupported
+}
+
+func (*terminal) size() (int, int, error) {
+	return 0, 0, errUnsupported
+}
+
+func (*terminal) restore() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package tui is a full screen terminal UI for the frames seen by lcnMonitor.
+package tui
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+)
+
+const (
+	refreshInterval = 500 * time.Millisecond
+
+	enterScreen = "\x1b[?1049h\x1b[?25l" // alternate screen, hide cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Store is the source of the frames shown, implemented by monitor.DataStore.
+type Store interface {
+	Entries() []monitor.Entry
+	Diff(s monitor.Stream) (monitor.Diff, bool)
+	Mark(name string) monitor.Event
+	Events() []monitor.Event
+}
+
+// Outputs is the source of the output states shown, implemented by state.Registry.
+type Outputs interface {
+	Affected(pkt *lcn.LcnPacket) ([8]state.Output, bool)
+}
+
+// Run shows the entries of store and the output states of outputs on stdin/stdout until q is pressed or ctx is done.
+func Run(ctx context.Context, store Store, outputs Outputs) error {
+	term, err := openTerminal(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_, _ = io.WriteString(os.Stdout, leaveScreen)
+		_ = term.restore()
+	}()
+
+	_, _ = io.WriteString(os.Stdout, enterScreen)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan []Key)
+	go readKeys(ctx, os.Stdin, keys)
+
+	model := NewModel()
+	ticker := time.NewTicker(refreshInterval)
+
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.size()
+		if err != nil || width == 0 || height == 0 {
+			width, height = defaultWidth, defaultHeight
+		}
+
+		model.SetEntries(store.Entries())
+		model.SetEvents(store.Events())
+
+		if e, ok := model.Selected(); ok && model.Detail() {
+			diff, _ := store.Diff(monitor.StreamOf(&e.Packet))
+			model.SetDiff(diff)
+
+			if module, ok := outputs.Affected(&e.Packet); ok {
+				model.SetOutputs(module[:])
+			} else {
+				model.SetOutputs(nil)
+			}
+		}
+
+		if err := draw(os.Stdout, model.Render(width, height)); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case pressed := <-keys:
+			for _, k := range pressed {
+				switch model.HandleKey(k, model.TableHeight(height)) {
+				case ActionQuit:
+					return nil
+				case ActionMark:
+					store.Mark("")
+				case ActionNone:
+				}
+			}
+		}
+	}
+}
+
+func readKeys(ctx context.Context, in io.Reader, keys chan<- []Key) {
+	buf := make([]byte, 64) //nolint:gomnd
+
+	for ctx.Err() == nil {
+		n, err := in.Read(buf)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		select {
+		case keys <- ParseKeys(buf[:n]):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// draw overwrites the screen in a single write to avoid flicker.
+func draw(out io.Writer, lines []string) error {
+	var b strings.Builder
+
+	b.WriteString(home)
+
+	for i, line := range lines {
+		b.WriteString(line)
+		b.WriteString(clearLine)
+
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	b.WriteString(clearBelow)
+
+	_, err := io.WriteString(out, b.String())
+
+	return errors.Wrap(err, "cannot draw")
+}
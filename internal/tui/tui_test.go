@@ -0,0 +1,190 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/monitor"
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/internal/state"
+	"github.com/MyChaOS87/reverseLCN/internal/tui"
+)
+
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		keys  []tui.Key
+	}{
+		{
+			name:  "runes",
+			input: "s/ü",
+			keys:  []tui.Key{{Rune: 's'}, {Rune: '/'}, {Rune: 'ü'}},
+		},
+		{
+			name:  "arrows and pages",
+			input: "\x1b[A\x1b[B\x1b[5~\x1b[6~\x1bOA",
+			keys: []tui.Key{
+				{Code: tui.KeyUp}, {Code: tui.KeyDown}, {Code: tui.KeyPageUp}, {Code: tui.KeyPageDown}, {Code: tui.KeyUp},
+			},
+		},
+		{
+			name:  "enter, backspace and escape",
+			input: "\r\x7f\x1b",
+			keys:  []tui.Key{{Code: tui.KeyEnter}, {Code: tui.KeyBackspace}, {Code: tui.KeyEscape}},
+		},
+		{
+			name:  "unknown sequences are dropped",
+			input: "\x1b[15~q",
+			keys:  []tui.Key{{Rune: 'q'}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.keys, tui.ParseKeys([]byte(tt.input)))
+		})
+	}
+}
+
+func entry(src, dst byte, name, command string, count int, seen int64) monitor.Entry {
+	return monitor.Entry{
+		Packet:   lcn.LcnPacket{Src: src, Seg: 0, Dst: dst, Cmd: 0x68, Payload: []byte{0x30, 0x01}},
+		LastSeen: time.Unix(seen, 0),
+		Count:    count,
+		Changed:  []bool{false, true},
+		Src:      name,
+		Dst:      "target",
+		Command:  command,
+	}
+}
+
+func names(m *tui.Model) []string {
+	var result []string
+
+	for _, line := range m.Render(200, 20)[2:] {
+		if fields := strings.Fields(line); len(fields) > 2 && strings.HasPrefix(fields[2], "src") {
+			result = append(result, fields[2])
+		}
+	}
+
+	return result
+}
+
+func keys(m *tui.Model, input string) {
+	for _, k := range tui.ParseKeys([]byte(input)) {
+		m.HandleKey(k, 10)
+	}
+}
+
+func TestModel(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.SetEntries([]monitor.Entry{
+		entry(1, 10, "src-a", "StatusReport", 5, 3),
+		entry(2, 11, "src-b", "Relais", 9, 1),
+		entry(3, 12, "src-c", "StatusReport", 1, 2),
+	})
+
+	assert.Equal(t, []string{"src-a", "src-c", "src-b"}, names(m), "most recent first")
+
+	keys(m, "s")
+	assert.Equal(t, []string{"src-b", "src-a", "src-c"}, names(m), "by count")
+
+	keys(m, "r")
+	assert.Equal(t, []string{"src-c", "src-a", "src-b"}, names(m), "reversed")
+
+	keys(m, "/status\r")
+	assert.Equal(t, []string{"src-c", "src-a"}, names(m), "filtered by command")
+
+	keys(m, "\x1b")
+	assert.Len(t, names(m), 3, "filter cleared")
+
+	// the selection sticks to its frame when the order changes
+	selected, ok := m.Selected()
+	require.True(t, ok)
+	assert.Equal(t, "src-a", selected.Src)
+
+	keys(m, "\x1b[B")
+	selected, _ = m.Selected()
+	assert.Equal(t, "src-b", selected.Src)
+
+	keys(m, "r")
+	selected, _ = m.Selected()
+	assert.Equal(t, "src-b", selected.Src)
+
+	assert.Equal(t, tui.ActionMark, m.HandleKey(tui.Key{Rune: 'm'}, 10))
+	assert.Equal(t, tui.ActionQuit, m.HandleKey(tui.Key{Rune: 'q'}, 10))
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.SetEntries([]monitor.Entry{entry(1, 10, "src-a", "StatusReport", 5, 3)})
+
+	lines := m.Render(200, 20)
+	assert.Len(t, lines, 20)
+	assert.Contains(t, lines[2], "30\x1b[1;33m01\x1b[22;39m", "changed byte highlighted")
+
+	keys(m, "\r")
+
+	screen := strings.Join(m.Render(200, 20), "\n")
+	assert.Contains(t, screen, "raw:     80 04 6a 00 0a 68 30")
+	assert.Contains(t, screen, "Src  80 10000000")
+	assert.Contains(t, screen, "\x1b[1;33mP1   01 00000001\x1b[22;39m")
+	assert.Contains(t, screen, "outputs: -")
+
+	outputs := make([]state.Output, 8)
+	for i := range outputs {
+		outputs[i] = state.Output{Key: state.Key{Segment: 0, Module: 1, Output: i}, Known: i < 2, On: i == 0}
+	}
+
+	m.SetOutputs(outputs)
+
+	screen = strings.Join(m.Render(200, 20), "\n")
+	assert.Contains(t, screen, "outputs: 0:1  1:\x1b[1;33mon\x1b[22;39m 2:off 3:?")
+
+	event := monitor.Event{Time: time.Unix(2, 0), Name: "mark 1"}
+	m.SetDiff(monitor.Diff{
+		Stream:      monitor.Stream{Src: 1, Dst: 10, Cmd: 0x68},
+		Frames:      3,
+		Changes:     2,
+		ByteChanges: []int{0, 2},
+		BitChanges:  [][8]int{{}, {2, 1}},
+		Samples: []monitor.Sample{
+			{Time: time.Unix(1, 0), Payload: []byte{0x30, 0x00}},
+			{Time: time.Unix(3, 0), Payload: []byte{0x30, 0x01}, Flipped: []byte{0, 0x01}, Event: &event},
+		},
+	})
+
+	screen = strings.Join(m.Render(200, 30), "\n")
+	assert.Contains(t, screen, "3 frames, 2 changed")
+	assert.Contains(t, screen, "P1      2x  bits 7..0:  0  0  0  0  0  0  1  2")
+	assert.Contains(t, screen, "30 \x1b[1;33m01\x1b[22;39m  1s after mark 1")
+
+	for _, line := range m.Render(40, 20) {
+		assert.LessOrEqual(t, len([]rune(stripped(line))), 40)
+	}
+}
+
+func stripped(line string) string {
+	for {
+		start := strings.Index(line, "\x1b[")
+		if start < 0 {
+			return line
+		}
+
+		end := strings.IndexFunc(line[start+2:], func(r rune) bool { return r >= '@' && r <= '~' })
+		line = line[:start] + line[start+2+end+1:]
+	}
+}
@@ -5,12 +5,32 @@ import "context"
 type Broker interface {
 	Run(ctx context.Context, cancel context.CancelFunc)
 	Topic(topic string) Topic
+	// OnConnect registers a callback for every (re-)connect, it is called immediately if already connected.
+	OnConnect(callback func())
+	// Close disconnects, giving pending publishes a moment to complete.
+	Close()
+	Stats() Stats
+}
+
+// Stats are counters of a broker since it was created.
+type Stats struct {
+	Published       uint64
+	PublishFailures uint64 // publishes failed or attempted while disconnected
+	Connects        uint64
+	ConnectionsLost uint64
+	Buffered        int    // publishes waiting for a connection
+	Dropped         uint64 // publishes dropped because the buffer was full or disabled
 }
 
 type Topic interface {
 	PublishString(s string)
+	PublishStringRetained(s string)
 	Publish(i interface{})
 	Subscribe(hint interface{}, callback CallbackFunction)
+	SubscribeString(callback StringCallbackFunction)
 }
 
-type CallbackFunction func(topic string, data interface{})
+type (
+	CallbackFunction       func(topic string, data interface{})
+	StringCallbackFunction func(topic string, data string)
+)
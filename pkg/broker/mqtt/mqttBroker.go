@@ -4,13 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
 
 	"github.com/MyChaOS87/reverseLCN/pkg/broker"
 	"github.com/MyChaOS87/reverseLCN/pkg/log"
 )
 
+// closeTimeout bounds how long Close waits for publishes in flight.
+const closeTimeout = 2 * time.Second
+
 var (
 	_ broker.Broker = &mqttBroker{}
 	_ broker.Topic  = &mqttTopic{}
@@ -18,17 +25,173 @@ var (
 
 type mqttTopic struct {
 	topic  string
-	client mqtt.Client
+	broker *mqttBroker
+}
+
+type subscription struct {
+	topic    string
+	callback mqtt.MessageHandler
+}
+
+type publication struct {
+	topic    string
+	data     string
+	retained bool
 }
 
 type mqttBroker struct {
 	client mqtt.Client
+
+	mutex            sync.Mutex
+	onConnectHandler []func()
+	subscriptions    []subscription
+
+	bufferMutex sync.Mutex
+	buffer      []publication // publishes waiting for a connection, oldest first
+	bufferSize  int
+
+	inflight sync.WaitGroup // publishes handed to the client, waited for by Close
+
+	published       atomic.Uint64
+	publishFailures atomic.Uint64
+	connects        atomic.Uint64
+	connectionsLost atomic.Uint64
+	dropped         atomic.Uint64
+}
+
+func (p *mqttBroker) OnConnect(callback func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.onConnectHandler = append(p.onConnectHandler, callback)
+
+	if p.client.IsConnected() {
+		go callback()
+	}
+}
+
+// onConnect restores all subscriptions and sends buffered publishes before notifying callbacks.
+func (p *mqttBroker) onConnect(mqtt.Client) {
+	p.connects.Add(1)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, s := range p.subscriptions {
+		p.subscribe(s)
+	}
+
+	p.flush()
+
+	for _, callback := range p.onConnectHandler {
+		go callback()
+	}
+}
+
+func (p *mqttBroker) onConnectionLost(_ mqtt.Client, err error) {
+	p.connectionsLost.Add(1)
+
+	log.Warnf("Connection to MQTT Broker lost: %s", err)
+}
+
+func (p *mqttBroker) Stats() broker.Stats {
+	p.bufferMutex.Lock()
+	buffered := len(p.buffer)
+	p.bufferMutex.Unlock()
+
+	return broker.Stats{
+		Published:       p.published.Load(),
+		PublishFailures: p.publishFailures.Load(),
+		Connects:        p.connects.Load(),
+		ConnectionsLost: p.connectionsLost.Load(),
+		Buffered:        buffered,
+		Dropped:         p.dropped.Load(),
+	}
 }
 
 func (p *mqttBroker) Topic(topicName string) broker.Topic {
 	return &mqttTopic{
 		topic:  topicName,
-		client: p.client,
+		broker: p,
+	}
+}
+
+func (p *mqttBroker) subscribe(s subscription) {
+	token := p.client.Subscribe(s.topic, 0, s.callback)
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			log.Errorf("Error on Subscribe to %s: %s", s.topic, err)
+		} else {
+			log.Infof("Successfully Subscribed to %s", s.topic)
+		}
+	}()
+}
+
+func (p *mqttBroker) publish(pub publication) {
+	if !p.client.IsConnectionOpen() {
+		p.publishFailures.Add(1)
+		p.enqueue(pub)
+
+		return
+	}
+
+	token := p.client.Publish(pub.topic, 1, pub.retained, pub.data)
+
+	p.inflight.Add(1)
+
+	go func() {
+		defer p.inflight.Done()
+
+		token.Wait()
+		if err := token.Error(); err != nil {
+			log.Errorf("Error on Publish to %s: %s", pub.topic, err)
+			p.publishFailures.Add(1)
+
+			// the client redelivers publishes it accepted itself, buffering them too would duplicate them
+			if errors.Is(err, mqtt.ErrNotConnected) {
+				p.enqueue(pub)
+			}
+		} else {
+			p.published.Add(1)
+			log.Infof("Successfully Published %s to %s", pub.data, pub.topic)
+		}
+	}()
+}
+
+// enqueue buffers a publish until the next connect, dropping the oldest one if the buffer is full.
+func (p *mqttBroker) enqueue(pub publication) {
+	if p.bufferSize <= 0 {
+		log.Errorf("Not connected, dropping publish to %s", pub.topic)
+		p.dropped.Add(1)
+
+		return
+	}
+
+	p.bufferMutex.Lock()
+	defer p.bufferMutex.Unlock()
+
+	if len(p.buffer) >= p.bufferSize {
+		p.buffer = p.buffer[1:]
+
+		log.Warnf("Publish buffer full, dropped %d publishes so far", p.dropped.Add(1))
+	}
+
+	p.buffer = append(p.buffer, pub)
+}
+
+func (p *mqttBroker) flush() {
+	p.bufferMutex.Lock()
+	buffer := p.buffer
+	p.buffer = nil
+	p.bufferMutex.Unlock()
+
+	if len(buffer) > 0 {
+		log.Infof("Publishing %d buffered messages", len(buffer))
+	}
+
+	for _, pub := range buffer {
+		p.publish(pub)
 	}
 }
 
@@ -49,20 +212,41 @@ func (p *mqttBroker) Run(ctx context.Context, cancel context.CancelFunc) {
 	//}()
 }
 
-func (t *mqttTopic) publishInternal(data string) {
-	token := t.client.Publish(t.topic, 1, false, data)
+// Close disconnects once the publishes handed to the client, like the offline state, are done or closeTimeout
+// passed.
+func (p *mqttBroker) Close() {
+	const quiesce = 250
+
+	done := make(chan struct{})
+
 	go func() {
-		token.Wait()
-		if err := token.Error(); err != nil {
-			log.Errorf("Error on Publish to %s: %s", t.topic, err)
-		} else {
-			log.Infof("Successfully Published %s to %s", data, t.topic)
-		}
+		p.inflight.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		log.Warnf("Disconnecting with publishes in flight after %s", closeTimeout)
+	}
+
+	p.client.Disconnect(quiesce)
+}
+
+func (t *mqttTopic) publishInternal(data string, retained bool) {
+	t.broker.publish(publication{
+		topic:    t.topic,
+		data:     data,
+		retained: retained,
+	})
 }
 
 func (t *mqttTopic) PublishString(s string) {
-	t.publishInternal(s)
+	t.publishInternal(s, false)
+}
+
+func (t *mqttTopic) PublishStringRetained(s string) {
+	t.publishInternal(s, true)
 }
 
 func (t *mqttTopic) Publish(data interface{}) {
@@ -72,7 +256,7 @@ func (t *mqttTopic) Publish(data interface{}) {
 		return
 	}
 
-	t.publishInternal(string(b))
+	t.publishInternal(string(b), false)
 }
 
 func (t *mqttTopic) Subscribe(hint interface{}, callback broker.CallbackFunction) {
@@ -90,15 +274,30 @@ func (t *mqttTopic) Subscribe(hint interface{}, callback broker.CallbackFunction
 		callback(message.Topic(), payload.Interface())
 	}
 
-	token := t.client.Subscribe(t.topic, 0, internalCallback)
-	go func() {
-		token.Wait()
-		if err := token.Error(); err != nil {
-			log.Errorf("Error on Subscribe to %s: %s", t.topic, err)
-		} else {
-			log.Infof("Successfully Subscribed to %s", t.topic)
-		}
-	}()
+	t.subscribeInternal(internalCallback)
+}
+
+func (t *mqttTopic) SubscribeString(callback broker.StringCallbackFunction) {
+	t.subscribeInternal(func(client mqtt.Client, message mqtt.Message) {
+		log.Debugf("got MQTT message: %s", message.Payload())
+		callback(message.Topic(), string(message.Payload()))
+	})
+}
+
+// subscribeInternal remembers the subscription to restore it on every connect.
+func (t *mqttTopic) subscribeInternal(callback mqtt.MessageHandler) {
+	t.broker.mutex.Lock()
+	defer t.broker.mutex.Unlock()
+
+	s := subscription{
+		topic:    t.topic,
+		callback: callback,
+	}
+	t.broker.subscriptions = append(t.broker.subscriptions, s)
+
+	if t.broker.client.IsConnectionOpen() {
+		t.broker.subscribe(s)
+	}
 }
 
 func NewBroker(options ...Option) broker.Broker {
@@ -108,9 +307,13 @@ func NewBroker(options ...Option) broker.Broker {
 		opt(config)
 	}
 
-	client := mqtt.NewClient(config.clientOptions)
-
-	return &mqttBroker{
-		client: client,
+	b := &mqttBroker{
+		bufferSize: config.publishBuffer,
 	}
+
+	config.clientOptions.SetOnConnectHandler(b.onConnect)
+	config.clientOptions.SetConnectionLostHandler(b.onConnectionLost)
+	b.client = mqtt.NewClient(config.clientOptions)
+
+	return b
 }
@@ -0,0 +1,177 @@
+package mqtt
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+)
+
+// fakeToken completes once done is closed, or right away without done.
+type fakeToken struct {
+	mqtt.Token
+
+	done <-chan struct{}
+	err  error
+}
+
+func (t fakeToken) Wait() bool {
+	if t.done != nil {
+		<-t.done
+	}
+
+	return true
+}
+
+func (t fakeToken) Error() error {
+	return t.err
+}
+
+type fakeClient struct {
+	mqtt.Client
+
+	mutex      sync.Mutex
+	connected  bool
+	published  []string
+	subscribed []string
+	token      fakeToken // returned by Publish
+	calls      []string  // publishes and disconnects, in order
+}
+
+func (c *fakeClient) IsConnectionOpen() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.connected
+}
+
+func (c *fakeClient) IsConnected() bool {
+	return c.IsConnectionOpen()
+}
+
+func (c *fakeClient) Publish(topic string, _ byte, _ bool, payload interface{}) mqtt.Token {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.published = append(c.published, topic+" "+payload.(string))
+	c.calls = append(c.calls, "publish "+topic)
+
+	return c.token
+}
+
+func (c *fakeClient) Disconnect(uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.calls = append(c.calls, "disconnect")
+}
+
+func (c *fakeClient) Subscribe(topic string, _ byte, _ mqtt.MessageHandler) mqtt.Token {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.subscribed = append(c.subscribed, topic)
+
+	return fakeToken{}
+}
+
+func (c *fakeClient) setConnected(connected bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.connected = connected
+}
+
+func TestResubscribeOnConnect(t *testing.T) {
+	client := new(fakeClient)
+	b := &mqttBroker{client: client, bufferSize: 10}
+
+	b.Topic("lcn/in").SubscribeString(func(string, string) {})
+	assert.Empty(t, client.subscribed)
+
+	client.setConnected(true)
+	b.onConnect(client)
+	assert.Equal(t, []string{"lcn/in"}, client.subscribed)
+
+	client.setConnected(false)
+	client.setConnected(true)
+	b.onConnect(client)
+	assert.Equal(t, []string{"lcn/in", "lcn/in"}, client.subscribed)
+}
+
+func TestPublishBuffer(t *testing.T) {
+	client := new(fakeClient)
+	b := &mqttBroker{client: client, bufferSize: 2}
+
+	b.Topic("a").PublishString("1")
+	b.Topic("b").PublishString("2")
+	b.Topic("c").PublishString("3")
+	assert.Empty(t, client.published)
+	assert.Equal(t, broker.Stats{PublishFailures: 3, Buffered: 2, Dropped: 1}, b.Stats())
+
+	client.setConnected(true)
+	b.onConnect(client)
+	assert.Equal(t, []string{"b 2", "c 3"}, client.published)
+
+	b.Topic("d").PublishStringRetained("4")
+	assert.Equal(t, []string{"b 2", "c 3", "d 4"}, client.published)
+
+	assert.Eventually(t, func() bool {
+		return b.Stats().Published == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(1), b.Stats().Connects)
+	assert.Zero(t, b.Stats().Buffered)
+}
+
+func TestCloseWaitsForPublishes(t *testing.T) {
+	done := make(chan struct{})
+
+	client := &fakeClient{connected: true, token: fakeToken{done: done}}
+	b := &mqttBroker{client: client}
+
+	b.Topic("lcn/bridge/state").PublishStringRetained("offline")
+
+	closed := make(chan struct{})
+
+	go func() {
+		b.Close()
+		close(closed)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, closed, "waits for the publish in flight")
+
+	close(done)
+	<-closed
+
+	assert.Equal(t, []string{"publish lcn/bridge/state", "disconnect"}, client.calls)
+}
+
+func TestFailedPublishes(t *testing.T) {
+	client := &fakeClient{connected: true, token: fakeToken{err: errors.New("connection lost")}}
+	b := &mqttBroker{client: client, bufferSize: 10}
+
+	// accepted by the client, which redelivers it on reconnect
+	b.Topic("a").PublishString("1")
+
+	assert.Eventually(t, func() bool {
+		return b.Stats().PublishFailures == 1
+	}, time.Second, time.Millisecond)
+	assert.Zero(t, b.Stats().Buffered)
+
+	// refused by the client, buffered until the next connect
+	client.mutex.Lock()
+	client.token = fakeToken{err: mqtt.ErrNotConnected}
+	client.mutex.Unlock()
+
+	b.Topic("b").PublishString("2")
+
+	assert.Eventually(t, func() bool {
+		return b.Stats().Buffered == 1
+	}, time.Second, time.Millisecond)
+}
@@ -2,6 +2,7 @@ package mqtt
 
 import (
 	"crypto/tls"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
@@ -10,6 +11,7 @@ type (
 	Option func(*Config)
 	Config struct {
 		clientOptions *mqtt.ClientOptions
+		publishBuffer int
 	}
 )
 
@@ -25,8 +27,50 @@ func TLS(tls *tls.Config) Option {
 	}
 }
 
+func ClientID(clientID string) Option {
+	return func(c *Config) {
+		c.clientOptions.SetClientID(clientID)
+	}
+}
+
+func Credentials(username, password string) Option {
+	return func(c *Config) {
+		c.clientOptions.SetUsername(username)
+		c.clientOptions.SetPassword(password)
+	}
+}
+
+func KeepAlive(keepAlive time.Duration) Option {
+	return func(c *Config) {
+		c.clientOptions.SetKeepAlive(keepAlive)
+	}
+}
+
+func CleanSession(clean bool) Option {
+	return func(c *Config) {
+		c.clientOptions.SetCleanSession(clean)
+	}
+}
+
+// Will sets the last will the broker publishes when the connection is lost without a proper disconnect.
+func Will(topic, payload string, qos byte, retained bool) Option {
+	return func(c *Config) {
+		c.clientOptions.SetWill(topic, payload, qos, retained)
+	}
+}
+
+// PublishBuffer sets how many publishes are kept while the broker is not connected, 0 drops them.
+func PublishBuffer(size int) Option {
+	return func(c *Config) {
+		c.publishBuffer = size
+	}
+}
+
 func newDefaultConfig() *Config {
+	const publishBuffer = 1000
+
 	return &Config{
 		clientOptions: mqtt.NewClientOptions(),
+		publishBuffer: publishBuffer,
 	}
 }
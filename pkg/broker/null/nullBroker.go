@@ -19,6 +19,16 @@ var (
 func (nullBroker) Run(context.Context, context.CancelFunc) {
 }
 
+func (nullBroker) OnConnect(func()) {
+}
+
+func (nullBroker) Close() {
+}
+
+func (nullBroker) Stats() broker.Stats {
+	return broker.Stats{}
+}
+
 func (nullBroker) Topic(string) broker.Topic {
 	return &nullTopic{}
 }
@@ -28,9 +38,14 @@ func (nullTopic) Publish(interface{}) {
 
 func (nullTopic) PublishString(string) {}
 
+func (nullTopic) PublishStringRetained(string) {}
+
 func (nullTopic) Subscribe(hint interface{}, callback broker.CallbackFunction) {
 }
 
+func (nullTopic) SubscribeString(callback broker.StringCallbackFunction) {
+}
+
 func NewBroker() broker.Broker {
 	return &nullBroker{}
 }
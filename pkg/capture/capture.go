@@ -0,0 +1,76 @@
+// Package capture records the traffic of a serial port into a file and replays it.
+//
+// A capture is a JSON lines file. The first line is a Header, every other line a Record
+// holding either raw bytes as they were read from or written to the device, or a decoded frame.
+package capture
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+const (
+	Format  = "reverseLCN-capture"
+	Version = 1
+
+	dirRx = "rx"
+	dirTx = "tx"
+)
+
+var (
+	ErrInvalidCapture = errors.New("invalid capture")
+	ErrReadOnly       = errors.New("capture replay cannot send")
+)
+
+type Header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Start   time.Time `json:"start"`
+}
+
+// Record is a single line of a capture, either Raw or Frame is set.
+type Record struct {
+	Time  time.Time `json:"t"`
+	Dir   string    `json:"dir"`
+	Raw   Bytes     `json:"raw,omitempty"`
+	Frame Bytes     `json:"frame,omitempty"`
+	Text  string    `json:"text,omitempty"` // human readable frame, ignored when reading
+}
+
+// Bytes are written as hex string.
+type Bytes []byte
+
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+func (b *Bytes) UnmarshalText(text []byte) error {
+	buf, err := hex.DecodeString(string(text))
+	if err != nil {
+		return errors.Wrap(ErrInvalidCapture, err.Error())
+	}
+
+	*b = buf
+
+	return nil
+}
+
+func (r *Record) Direction() serial.Direction {
+	if r.Dir == dirTx {
+		return serial.DirectionTx
+	}
+
+	return serial.DirectionRx
+}
+
+func direction(dir serial.Direction) string {
+	if dir == serial.DirectionTx {
+		return dirTx
+	}
+
+	return dirRx
+}
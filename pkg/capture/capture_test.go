@@ -0,0 +1,141 @@
+package capture_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/capture"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/plain"
+)
+
+func writeCapture(t *testing.T, out io.Writer, start time.Time) {
+	t.Helper()
+
+	w, err := capture.NewWriter(out, start)
+	require.NoError(t, err)
+
+	frame := plain.Plain{0xa8, 0x06}
+
+	w.Raw(start.Add(10*time.Millisecond), serial.DirectionRx, []byte{0xa8, 0x06})
+	w.Frame(start.Add(10*time.Millisecond), serial.DirectionRx, &frame)
+	w.Raw(start.Add(20*time.Millisecond), serial.DirectionTx, []byte{0x01})
+	w.Raw(start.Add(30*time.Millisecond), serial.DirectionRx, []byte{0x75})
+}
+
+func TestRoundTrip(t *testing.T) {
+	start := time.Date(2023, 3, 5, 18, 21, 7, 0, time.UTC)
+
+	var buf bytes.Buffer
+
+	writeCapture(t, &buf, start)
+
+	reader, err := capture.NewReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, capture.Format, reader.Header.Format)
+	assert.True(t, start.Equal(reader.Header.Start))
+
+	var records []*capture.Record
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+
+		records = append(records, record)
+	}
+
+	require.Len(t, records, 4)
+	assert.Equal(t, capture.Bytes{0xa8, 0x06}, records[0].Raw)
+	assert.Equal(t, serial.DirectionRx, records[0].Direction())
+	assert.Equal(t, capture.Bytes("a806"), records[1].Frame)
+	assert.Equal(t, serial.DirectionTx, records[2].Direction())
+	assert.True(t, start.Add(30*time.Millisecond).Equal(records[3].Time))
+}
+
+func TestFrameVerbatim(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := capture.NewWriter(&buf, time.Now())
+	require.NoError(t, err)
+
+	// accepted despite its checksum, it is recorded as received
+	frame := []byte{0xa8, 0x06, 0x76, 0x00, 0x04, 0x68, 0x30, 0x00}
+	pkt, err := lcn.NewCodec(lcn.AcceptInvalid(true)).Deserialize(frame)
+	require.NoError(t, err)
+
+	w.Frame(time.Now(), serial.DirectionRx, pkt)
+
+	reader, err := capture.NewReader(&buf)
+	require.NoError(t, err)
+
+	record, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, capture.Bytes(frame), record.Frame)
+}
+
+func TestInvalidCapture(t *testing.T) {
+	_, err := capture.NewReader(bytes.NewBufferString(`{"format":"something else"}`))
+	assert.ErrorIs(t, err, capture.ErrInvalidCapture)
+
+	_, err = capture.NewReader(bytes.NewBufferString("not json"))
+	assert.ErrorIs(t, err, capture.ErrInvalidCapture)
+}
+
+func TestReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bus.capture")
+
+	file, err := os.Create(filename)
+	require.NoError(t, err)
+	writeCapture(t, file, time.Now())
+	require.NoError(t, file.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replay := capture.NewReplay(filename, capture.Speed(0))
+
+	var states []serial.State
+
+	replay.OnStateChange(func(state serial.State) {
+		states = append(states, state)
+	})
+
+	var received []string
+
+	replay.Run(ctx, cancel, func(pkt packet.Packet) {
+		received = append(received, pkt.ToString())
+	})
+
+	select {
+	case <-replay.Done():
+	case <-time.After(time.Second):
+		t.Fatal("replay did not finish")
+	}
+
+	// plain packets are single bytes, sent bytes are not replayed
+	assert.Equal(t, []string{"a8", "06", "75"}, received)
+	assert.Equal(t, uint64(3), replay.Stats().Received)
+	assert.Equal(t, []serial.State{
+		serial.StateDisconnected,
+		serial.StateConnecting,
+		serial.StateConnected,
+		serial.StateDisconnected,
+	}, states)
+
+	assert.ErrorIs(t, replay.Send(ctx, []byte{0x01}), capture.ErrReadOnly)
+}
@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/plain"
+)
+
+type (
+	Option func(*Config)
+	Config struct {
+		deserializer packet.Deserializer
+		minLength    int
+		speed        float64
+		maxGap       time.Duration
+		maxFrameAge  time.Duration
+		observers    []serial.Observer
+	}
+)
+
+func Deserializer(deserializer packet.Deserializer) Option {
+	return func(c *Config) {
+		c.deserializer = deserializer
+	}
+}
+
+func MinLength(minLength int) Option {
+	return func(c *Config) {
+		c.minLength = minLength
+	}
+}
+
+// Speed sets how much faster than recorded a capture is replayed, 0 replays without any delay.
+func Speed(speed float64) Option {
+	return func(c *Config) {
+		c.speed = speed
+	}
+}
+
+// MaxGap sets how long a pause within a frame may be before the incomplete frame is dropped, 0 disables it.
+// The gap is measured between the recorded timestamps.
+func MaxGap(gap time.Duration) Option {
+	return func(c *Config) {
+		c.maxGap = gap
+	}
+}
+
+// MaxFrameAge sets how long receiving a frame may take before the incomplete frame is dropped, 0 disables it.
+func MaxFrameAge(age time.Duration) Option {
+	return func(c *Config) {
+		c.maxFrameAge = age
+	}
+}
+
+// Observe adds an observer seeing the replayed bytes and frames with their recorded timestamps.
+func Observe(observer serial.Observer) Option {
+	return func(c *Config) {
+		c.observers = append(c.observers, observer)
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		deserializer: plain.Deserialize,
+		minLength:    1,
+		speed:        1,
+		maxGap:       50 * time.Millisecond,
+		maxFrameAge:  200 * time.Millisecond,
+	}
+}
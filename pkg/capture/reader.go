@@ -0,0 +1,82 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Reader reads the records of a capture.
+type Reader struct {
+	Header Header
+
+	decoder *json.Decoder
+	closer  io.Closer
+}
+
+// Next returns the next record or io.EOF at the end of the capture.
+func (r *Reader) Next() (*Record, error) {
+	record := &Record{}
+
+	err := r.decoder.Decode(record)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidCapture, err.Error())
+	}
+
+	return record, nil
+}
+
+// Close closes the underlying file, if the reader was created by Open.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+
+	return r.closer.Close()
+}
+
+// NewReader reads and checks the header of a capture.
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{
+		decoder: json.NewDecoder(bufio.NewReader(in)),
+	}
+
+	if err := r.decoder.Decode(&r.Header); err != nil {
+		return nil, errors.Wrapf(ErrInvalidCapture, "cannot read header: %s", err)
+	}
+
+	if r.Header.Format != Format {
+		return nil, errors.Wrapf(ErrInvalidCapture, "unknown format %q", r.Header.Format)
+	}
+
+	if r.Header.Version > Version {
+		return nil, errors.Wrapf(ErrInvalidCapture, "unsupported version %d", r.Header.Version)
+	}
+
+	return r, nil
+}
+
+func Open(filename string) (*Reader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open capture %s", filename)
+	}
+
+	r, err := NewReader(file)
+	if err != nil {
+		file.Close()
+
+		return nil, errors.Wrapf(err, "capture %s", filename)
+	}
+
+	r.closer = file
+
+	return r, nil
+}
@@ -0,0 +1,206 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var _ serial.Port = &Replay{}
+
+// Replay is a serial.Port feeding the received bytes of a capture through a chunker.
+// Sent bytes of the capture are skipped, sending to a replay fails with ErrReadOnly.
+type Replay struct {
+	filename     string
+	chunkerMutex sync.Mutex
+	chunker      chunker.Chunker
+	speed        float64
+
+	observers []serial.Observer
+	recorded  time.Time // timestamp of the record being replayed
+
+	received atomic.Uint64
+	done     chan struct{}
+
+	stateMutex     sync.Mutex
+	state          serial.State
+	stateCallbacks []serial.StateCallback
+}
+
+func (r *Replay) Run(ctx context.Context, cancel context.CancelFunc, eject chunker.EjectFunc) {
+	go func() {
+		defer close(r.done)
+		defer r.setState(serial.StateDisconnected)
+
+		r.setState(serial.StateConnecting)
+
+		reader, err := Open(r.filename)
+		if err != nil {
+			log.Errorf("Cannot replay: %s", err)
+			cancel()
+
+			return
+		}
+		defer reader.Close()
+
+		r.setState(serial.StateConnected)
+
+		if err := r.replay(ctx, reader, eject); err != nil {
+			log.Errorf("Replay of %s stopped: %s", r.filename, err)
+
+			return
+		}
+
+		log.Infof("Replay of %s finished, %d packets", r.filename, r.received.Load())
+	}()
+}
+
+func (r *Replay) replay(ctx context.Context, reader *Reader, eject chunker.EjectFunc) error {
+	var first time.Time
+
+	start := time.Now()
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(record.Raw) == 0 || record.Direction() != serial.DirectionRx {
+			continue
+		}
+
+		if first.IsZero() {
+			first = record.Time
+		}
+
+		if r.speed > 0 {
+			offset := time.Duration(float64(record.Time.Sub(first)) / r.speed)
+
+			select {
+			case <-time.After(time.Until(start.Add(offset))):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		r.recorded = record.Time
+
+		for _, o := range r.observers {
+			o.Raw(record.Time, serial.DirectionRx, record.Raw)
+		}
+
+		r.chunkerMutex.Lock()
+		r.chunker.Collect(record.Raw, func(pkt packet.Packet) {
+			r.received.Add(1)
+
+			for _, o := range r.observers {
+				o.Frame(record.Time, serial.DirectionRx, pkt)
+			}
+
+			eject(pkt)
+		})
+		r.chunkerMutex.Unlock()
+	}
+}
+
+func (r *Replay) discard(buf []byte, err error) {
+	for _, o := range r.observers {
+		if d, ok := o.(serial.DiscardObserver); ok {
+			d.Discard(r.recorded, buf, err)
+		}
+	}
+}
+
+// Done is closed once the whole capture was replayed or the replay stopped.
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *Replay) Send(_ context.Context, buf []byte) error {
+	log.Warnf("Not sending %x, replaying %s", buf, r.filename)
+
+	return ErrReadOnly
+}
+
+func (r *Replay) Request(ctx context.Context, buf []byte, _ serial.Matcher) (packet.Packet, error) {
+	return nil, r.Send(ctx, buf)
+}
+
+func (r *Replay) QueueDepth() int {
+	return 0
+}
+
+func (r *Replay) State() serial.State {
+	r.stateMutex.Lock()
+	defer r.stateMutex.Unlock()
+
+	return r.state
+}
+
+func (r *Replay) OnStateChange(callback serial.StateCallback) {
+	r.stateMutex.Lock()
+	r.stateCallbacks = append(r.stateCallbacks, callback)
+	state := r.state
+	r.stateMutex.Unlock()
+
+	callback(state)
+}
+
+func (r *Replay) setState(state serial.State) {
+	r.stateMutex.Lock()
+	r.state = state
+	callbacks := append([]serial.StateCallback{}, r.stateCallbacks...)
+	r.stateMutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(state)
+	}
+}
+
+func (r *Replay) Stats() serial.Stats {
+	r.chunkerMutex.Lock()
+	stats := serial.ChunkerStats(r.chunker.Stats())
+	r.chunkerMutex.Unlock()
+
+	stats.Received = r.received.Load()
+
+	return stats
+}
+
+func NewReplay(filename string, options ...Option) *Replay {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
+	r := &Replay{
+		filename:  filename,
+		speed:     config.speed,
+		observers: config.observers,
+		done:      make(chan struct{}),
+	}
+
+	r.chunker = chunker.NewChunker(config.deserializer, config.minLength,
+		chunker.OnDiscard(r.discard),
+		chunker.MaxGap(config.maxGap),
+		chunker.MaxAge(config.maxFrameAge),
+		chunker.Clock(func() time.Time { return r.recorded }))
+
+	return r
+}
@@ -0,0 +1,112 @@
+package capture
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var _ serial.Observer = &Writer{}
+
+// Writer records traffic into a capture, add it to a port with serial.Observe.
+type Writer struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+	failed  bool
+}
+
+func (w *Writer) Raw(t time.Time, dir serial.Direction, buf []byte) {
+	w.write(&Record{
+		Time: t,
+		Dir:  direction(dir),
+		Raw:  append(Bytes{}, buf...),
+	})
+}
+
+// Frame records a frame with the bytes it was received as, e.g. a wrong checksum is kept.
+func (w *Writer) Frame(t time.Time, dir serial.Direction, pkt packet.Packet) {
+	buf, err := packet.Bytes(pkt)
+	if err != nil {
+		log.Warnf("Cannot record %s: %s", pkt.ToNiceString(), err)
+
+		return
+	}
+
+	w.write(&Record{
+		Time:  t,
+		Dir:   direction(dir),
+		Frame: buf,
+		Text:  pkt.ToNiceString(),
+	})
+}
+
+func (w *Writer) write(record *Record) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.encoder.Encode(record); err != nil && !w.failed {
+		// only log once, a full disk would flood the log otherwise
+		log.Errorf("Cannot write capture: %s", err)
+
+		w.failed = true
+	}
+}
+
+// Close closes the underlying file, if the writer was created by Create.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closer == nil {
+		return nil
+	}
+
+	return w.closer.Close()
+}
+
+// NewWriter writes the header of a new capture to out.
+func NewWriter(out io.Writer, start time.Time) (*Writer, error) {
+	w := &Writer{
+		encoder: json.NewEncoder(out),
+	}
+	w.encoder.SetEscapeHTML(false)
+
+	err := w.encoder.Encode(&Header{
+		Format:  Format,
+		Version: Version,
+		Start:   start,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot write capture header")
+	}
+
+	return w, nil
+}
+
+// Create creates a capture file, an existing file is truncated.
+func Create(filename string) (*Writer, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create capture %s", filename)
+	}
+
+	w, err := NewWriter(file, time.Now())
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	w.closer = file
+
+	return w, nil
+}
@@ -0,0 +1,148 @@
+//nolint:gochecknoglobals
+package command
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var ErrPayloadInvalid = errors.Wrap(packet.ErrPacketInvalid, "LCN payload does not match command")
+
+// Command is the typed representation of a LCN command byte together with its payload.
+type Command interface {
+	Code() byte
+	Payload() ([]byte, error)
+	String() string
+}
+
+// DecodeFunc turns the payload of a packet with a known command byte into a Command.
+type DecodeFunc func(payload []byte) (Command, error)
+
+type entry struct {
+	name   string
+	decode DecodeFunc
+}
+
+// registryMutex guards registry, decoders may be registered while frames are decoded.
+var registryMutex sync.RWMutex
+
+var registry = map[byte]entry{
+	CodeRelais:       {name: "relais", decode: decodeRelais},
+	CodeStatusReport: {name: "statusReport", decode: decodeStatusReport},
+	CodeStatusQuery:  {name: "statusQuery", decode: decodeStatusQuery},
+}
+
+// Register adds or replaces the decoder for a command byte.
+func Register(code byte, name string, decode DecodeFunc) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry[code] = entry{
+		name:   name,
+		decode: decode,
+	}
+}
+
+func lookup(code byte) (entry, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	e, ok := registry[code]
+
+	return e, ok
+}
+
+// Name returns the registered name of a command byte, or its hex representation if unknown.
+func Name(code byte) string {
+	if e, ok := lookup(code); ok {
+		return e.name
+	}
+
+	return fmt.Sprintf("0x%02x", code)
+}
+
+// Known reports whether a decoder is registered for the command byte.
+func Known(code byte) bool {
+	_, ok := lookup(code)
+
+	return ok
+}
+
+// DecodePayload decodes a payload for the given command byte,
+// unknown commands and unknown variants of known commands are returned as *Raw.
+func DecodePayload(code byte, payload []byte) (Command, error) {
+	e, ok := lookup(code)
+	if !ok {
+		return newRaw(code, payload), nil
+	}
+
+	return e.decode(payload)
+}
+
+// Decode decodes the command and payload of a deserialized packet.
+func Decode(pkt *lcn.LcnPacket) (Command, error) {
+	return DecodePayload(pkt.Cmd, pkt.Payload)
+}
+
+// NewPacket builds a packet carrying cmd, ready to be serialized.
+func NewPacket(src, seg, dst byte, cmd Command) (*lcn.LcnPacket, error) {
+	payload, err := cmd.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lcn.LcnPacket{
+		Src:     src,
+		Seg:     seg,
+		Dst:     dst,
+		Cmd:     cmd.Code(),
+		Payload: payload,
+	}, nil
+}
+
+func testBit(b byte, bit int) bool {
+	return b&(1<<uint(bit)) != 0
+}
+
+func bitsToOutputs(b byte) [8]bool {
+	var outputs [8]bool
+
+	for i := range outputs {
+		outputs[i] = testBit(b, i)
+	}
+
+	return outputs
+}
+
+func outputsToBits(outputs [8]bool) byte {
+	var b byte
+
+	for i, on := range outputs {
+		if on {
+			b |= 1 << uint(i)
+		}
+	}
+
+	return b
+}
+
+func formatOutputs(outputs [8]bool) string {
+	s := ""
+
+	for i, on := range outputs {
+		if on {
+			if s != "" {
+				s += ","
+			}
+
+			s += fmt.Sprintf("%d", i+1)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,133 @@
+package command_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     byte
+		payload []byte
+		command command.Command
+	}{
+		{
+			name:    "relais toggle",
+			cmd:     0x13,
+			payload: []byte{0x00, 0x80},
+			command: &command.RelaisCommand{Outputs: [8]command.RelaisAction{7: command.RelaisToggle}},
+		},
+		{
+			name:    "relais mixed",
+			cmd:     0x13,
+			payload: []byte{0x03, 0x06},
+			command: &command.RelaisCommand{Outputs: [8]command.RelaisAction{
+				0: command.RelaisOn, 1: command.RelaisOff, 2: command.RelaisToggle,
+			}},
+		},
+		{
+			name:    "relais short",
+			cmd:     0x13,
+			payload: []byte{0x03},
+			command: &command.Raw{Cmd: 0x13, Data: []byte{0x03}},
+		},
+		{
+			name:    "relais status",
+			cmd:     0x68,
+			payload: []byte{0x30, 0x05},
+			command: &command.RelaisStatus{Outputs: [8]bool{0: true, 2: true}},
+		},
+		{
+			name:    "unknown status report",
+			cmd:     0x68,
+			payload: []byte{0x31, 0x05},
+			command: &command.Raw{Cmd: 0x68, Data: []byte{0x31, 0x05}},
+		},
+		{
+			name:    "status query",
+			cmd:     0x6E,
+			payload: []byte{0xFB, 0xFF},
+			command: &command.StatusQuery{Outputs: [8]bool{true, true, true, true, true, true, true, true}},
+		},
+		{
+			name:    "status response",
+			cmd:     0x6E,
+			payload: []byte{0x7B, 0x10},
+			command: &command.StatusQuery{Response: true, Outputs: [8]bool{4: true}},
+		},
+		{
+			name:    "unknown command",
+			cmd:     0x22,
+			payload: []byte{0x01, 0x02},
+			command: &command.Raw{Cmd: 0x22, Data: []byte{0x01, 0x02}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("%s_%s", t.Name(), tt.name), func(t *testing.T) {
+			cmd, err := command.Decode(&lcn.LcnPacket{Cmd: tt.cmd, Payload: tt.payload})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.command, cmd)
+
+			payload, err := cmd.Payload()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.payload, payload)
+			assert.Equal(t, tt.cmd, cmd.Code())
+		})
+	}
+}
+
+func TestNewPacket(t *testing.T) {
+	pkt, err := command.NewPacket(1, 0, 33, &command.RelaisCommand{
+		Outputs: [8]command.RelaisAction{7: command.RelaisToggle},
+	})
+	assert.NoError(t, err)
+
+	buf, err := pkt.Serialize()
+	assert.NoError(t, err)
+
+	decoded, err := lcn.Deserialize(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x13), decoded.(*lcn.LcnPacket).Cmd)
+	assert.Equal(t, []byte{0x00, 0x80}, decoded.(*lcn.LcnPacket).Payload)
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "relais", command.Name(0x13))
+	assert.Equal(t, "0x22", command.Name(0x22))
+	assert.True(t, command.Known(0x6E))
+	assert.False(t, command.Known(0x22))
+}
+
+func TestExpectResponse(t *testing.T) {
+	sent, err := command.NewPacket(1, 0, 33, &command.RelaisCommand{
+		Outputs: [8]command.RelaisAction{0: command.RelaisOn, 1: command.RelaisOff},
+	})
+	assert.NoError(t, err)
+
+	expect := command.ExpectResponse(sent)
+
+	assert.True(t, expect(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}}))
+	assert.True(t, expect(&lcn.LcnPacket{Src: 33, Dst: 1, Cmd: 0x6E, Payload: []byte{0x7B, 0x05}}))
+	assert.False(t, expect(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x02}}), "relais not applied")
+	assert.False(t, expect(&lcn.LcnPacket{Src: 34, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}}), "wrong module")
+	assert.False(t, expect(&lcn.LcnPacket{Src: 33, Dst: 4, Cmd: 0x22, Payload: []byte{0x30, 0x01}}), "unrelated command")
+
+	query, err := command.NewPacket(1, 0, 33, &command.StatusQuery{})
+	assert.NoError(t, err)
+	assert.True(t, command.ExpectResponse(query)(&lcn.LcnPacket{Src: 33, Dst: 1, Cmd: 0x6E, Payload: []byte{0x7B, 0x00}}))
+	assert.False(t, command.ExpectResponse(query)(&lcn.LcnPacket{Src: 33, Dst: 1, Cmd: 0x6E, Payload: []byte{0xFB, 0x00}}))
+}
+
+func TestIdempotent(t *testing.T) {
+	assert.True(t, command.Idempotent(&command.RelaisCommand{Outputs: [8]command.RelaisAction{command.RelaisOn}}))
+	assert.False(t, command.Idempotent(&command.RelaisCommand{Outputs: [8]command.RelaisAction{command.RelaisToggle}}))
+	assert.True(t, command.Idempotent(&command.StatusQuery{}))
+	assert.False(t, command.Idempotent(&command.Raw{Cmd: 0x22}))
+}
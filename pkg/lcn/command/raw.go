@@ -0,0 +1,36 @@
+package command
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+var _ Command = &Raw{}
+
+// Raw is a command that could not be decoded into anything more specific.
+type Raw struct {
+	Cmd  byte
+	Data []byte
+}
+
+func newRaw(code byte, payload []byte) *Raw {
+	data := make([]byte, len(payload))
+	copy(data, payload)
+
+	return &Raw{
+		Cmd:  code,
+		Data: data,
+	}
+}
+
+func (r *Raw) Code() byte {
+	return r.Cmd
+}
+
+func (r *Raw) Payload() ([]byte, error) {
+	return r.Data, nil
+}
+
+func (r *Raw) String() string {
+	return fmt.Sprintf("%s %s", Name(r.Cmd), hex.EncodeToString(r.Data))
+}
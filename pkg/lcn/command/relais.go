@@ -0,0 +1,123 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const CodeRelais byte = 0x13
+
+type RelaisAction byte
+
+const (
+	RelaisNoChange RelaisAction = iota
+	RelaisOn
+	RelaisOff
+	RelaisToggle
+)
+
+var _ Command = &RelaisCommand{}
+
+// RelaisCommand switches the 8 relais of the destination module,
+// the first payload byte forces outputs, the second one toggles them and both together switch off.
+type RelaisCommand struct {
+	Outputs [8]RelaisAction `json:"outputs"`
+}
+
+func (a RelaisAction) String() string {
+	switch a {
+	case RelaisNoChange:
+		return "NOCHANGE"
+	case RelaisOn:
+		return "ON"
+	case RelaisOff:
+		return "OFF"
+	case RelaisToggle:
+		return "TOGGLE"
+	default:
+		return fmt.Sprintf("RelaisAction(%d)", byte(a))
+	}
+}
+
+func (a RelaisAction) MarshalText() ([]byte, error) {
+	if a > RelaisToggle {
+		return nil, ErrPayloadInvalid
+	}
+
+	return []byte(a.String()), nil
+}
+
+func (a *RelaisAction) UnmarshalText(text []byte) error {
+	for action := RelaisNoChange; action <= RelaisToggle; action++ {
+		if strings.EqualFold(action.String(), string(text)) {
+			*a = action
+
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrPayloadInvalid, "unknown relais action %q", text)
+}
+
+func decodeRelais(payload []byte) (Command, error) {
+	if len(payload) != 2 {
+		return newRaw(CodeRelais, payload), nil
+	}
+
+	r := new(RelaisCommand)
+
+	for i := range r.Outputs {
+		force := testBit(payload[0], i)
+		toggle := testBit(payload[1], i)
+
+		switch {
+		case force && toggle:
+			r.Outputs[i] = RelaisOff
+		case force:
+			r.Outputs[i] = RelaisOn
+		case toggle:
+			r.Outputs[i] = RelaisToggle
+		}
+	}
+
+	return r, nil
+}
+
+func (r *RelaisCommand) Code() byte {
+	return CodeRelais
+}
+
+func (r *RelaisCommand) Payload() ([]byte, error) {
+	payload := make([]byte, 2)
+
+	for i, action := range r.Outputs {
+		switch action {
+		case RelaisNoChange:
+		case RelaisOn:
+			payload[0] |= 1 << uint(i)
+		case RelaisOff:
+			payload[0] |= 1 << uint(i)
+			payload[1] |= 1 << uint(i)
+		case RelaisToggle:
+			payload[1] |= 1 << uint(i)
+		default:
+			return nil, ErrPayloadInvalid
+		}
+	}
+
+	return payload, nil
+}
+
+func (r *RelaisCommand) String() string {
+	outputs := make([]string, 0, len(r.Outputs))
+
+	for i, action := range r.Outputs {
+		if action != RelaisNoChange {
+			outputs = append(outputs, fmt.Sprintf("%d:%s", i+1, action))
+		}
+	}
+
+	return "relais " + strings.Join(outputs, ",")
+}
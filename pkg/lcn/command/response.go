@@ -0,0 +1,92 @@
+package command
+
+import (
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+// Idempotent reports whether sending the command twice has the same effect as sending it once,
+// only idempotent commands may be retried.
+func Idempotent(cmd Command) bool {
+	switch c := cmd.(type) {
+	case *RelaisCommand:
+		for _, action := range c.Outputs {
+			if action == RelaisToggle {
+				return false
+			}
+		}
+
+		return true
+	case *StatusQuery:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExpectResponse returns a matcher accepting the packet the destination module answers sent with.
+//
+// Relais commands and status queries are acknowledged by a relais status or status response,
+// for relais commands it has to show the forced outputs. Any other command is acknowledged by any packet of the destination.
+func ExpectResponse(sent *lcn.LcnPacket) func(packet.Packet) bool {
+	sentCmd, err := Decode(sent)
+
+	return func(p packet.Packet) bool {
+		pkt, ok := p.(*lcn.LcnPacket)
+		if !ok || pkt.Src != sent.Dst {
+			return false
+		}
+
+		if err != nil {
+			return true
+		}
+
+		switch s := sentCmd.(type) {
+		case *RelaisCommand:
+			outputs, ok := reportedRelais(pkt)
+
+			return ok && relaisApplied(s, outputs)
+		case *StatusQuery:
+			_, ok := reportedRelais(pkt)
+
+			return ok
+		default:
+			return true
+		}
+	}
+}
+
+// reportedRelais returns the relais state if pkt reports it.
+func reportedRelais(pkt *lcn.LcnPacket) ([8]bool, bool) {
+	cmd, err := Decode(pkt)
+	if err != nil {
+		return [8]bool{}, false
+	}
+
+	switch c := cmd.(type) {
+	case *RelaisStatus:
+		return c.Outputs, true
+	case *StatusQuery:
+		return c.Outputs, c.Response
+	default:
+		return [8]bool{}, false
+	}
+}
+
+func relaisApplied(cmd *RelaisCommand, outputs [8]bool) bool {
+	for i, action := range cmd.Outputs {
+		switch action {
+		case RelaisOn:
+			if !outputs[i] {
+				return false
+			}
+		case RelaisOff:
+			if outputs[i] {
+				return false
+			}
+		case RelaisNoChange, RelaisToggle:
+		}
+	}
+
+	return true
+}
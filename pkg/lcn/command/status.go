@@ -0,0 +1,92 @@
+package command
+
+const (
+	CodeStatusReport byte = 0x68
+	CodeStatusQuery  byte = 0x6E
+)
+
+const (
+	statusReportRelais byte = 0x30
+
+	statusQueryRequest  byte = 0xFB
+	statusQueryResponse byte = 0x7B
+)
+
+// StatusReportTarget is the module relais status reports are taken from, captured reports were sent to module 4.
+// Reports to other targets are not trusted to carry relais until one is seen to.
+const StatusReportTarget = 4
+
+var (
+	_ Command = &RelaisStatus{}
+	_ Command = &StatusQuery{}
+)
+
+// RelaisStatus is the status report (0x68) a module sends after its relais changed.
+type RelaisStatus struct {
+	Outputs [8]bool `json:"outputs"`
+}
+
+// StatusQuery asks a module for its relais state, the module answers with the Response flag set.
+type StatusQuery struct {
+	Response bool    `json:"response"`
+	Outputs  [8]bool `json:"outputs"`
+}
+
+func decodeStatusReport(payload []byte) (Command, error) {
+	if len(payload) != 2 || payload[0] != statusReportRelais {
+		return newRaw(CodeStatusReport, payload), nil
+	}
+
+	return &RelaisStatus{
+		Outputs: bitsToOutputs(payload[1]),
+	}, nil
+}
+
+func (s *RelaisStatus) Code() byte {
+	return CodeStatusReport
+}
+
+func (s *RelaisStatus) Payload() ([]byte, error) {
+	return []byte{statusReportRelais, outputsToBits(s.Outputs)}, nil
+}
+
+func (s *RelaisStatus) String() string {
+	return "relaisStatus " + formatOutputs(s.Outputs)
+}
+
+// QueryAllRelais returns a query for all relais, queries on the bus carry the full mask 0xFF.
+func QueryAllRelais() *StatusQuery {
+	return &StatusQuery{Outputs: [8]bool{true, true, true, true, true, true, true, true}}
+}
+
+func decodeStatusQuery(payload []byte) (Command, error) {
+	if len(payload) != 2 || (payload[0] != statusQueryRequest && payload[0] != statusQueryResponse) {
+		return newRaw(CodeStatusQuery, payload), nil
+	}
+
+	return &StatusQuery{
+		Response: payload[0] == statusQueryResponse,
+		Outputs:  bitsToOutputs(payload[1]),
+	}, nil
+}
+
+func (s *StatusQuery) Code() byte {
+	return CodeStatusQuery
+}
+
+func (s *StatusQuery) Payload() ([]byte, error) {
+	kind := statusQueryRequest
+	if s.Response {
+		kind = statusQueryResponse
+	}
+
+	return []byte{kind, outputsToBits(s.Outputs)}, nil
+}
+
+func (s *StatusQuery) String() string {
+	if s.Response {
+		return "statusResponse " + formatOutputs(s.Outputs)
+	}
+
+	return "statusQuery " + formatOutputs(s.Outputs)
+}
@@ -0,0 +1,112 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+)
+
+const maxOutput = 8
+
+// Input is a frame to send, given either by its raw fields
+//
+//	{"seg":0,"dst":33,"cmd":19,"payload":"0080"}
+//
+// or as a high-level command on an output of a module
+//
+//	{"module":33,"output":1,"action":"on"}
+type Input struct {
+	Version int `json:"version"` // optional, Version
+
+	// raw fields, src defaults to the configured source
+	Src     *byte `json:"src"`
+	Seg     byte  `json:"seg"`
+	Dst     byte  `json:"dst"`
+	Cmd     *byte `json:"cmd"`
+	Payload Hex   `json:"payload"`
+
+	// high-level command, the segment defaults to the one of the module
+	Module  byte   `json:"module"`
+	Segment *byte  `json:"segment"`
+	Output  int    `json:"output"` // 1-8
+	Action  string `json:"action"` // on, off or toggle
+}
+
+// HighLevel reports whether the input is a command on an output rather than a raw frame.
+func (in *Input) HighLevel() bool {
+	return in.Action != ""
+}
+
+// Packet builds the frame to send, source is used unless the input names one, segmentOf
+// returns the segment of a module.
+func (in *Input) Packet(source byte, segmentOf func(module int) int) (*lcn.LcnPacket, error) {
+	if !in.HighLevel() {
+		if in.Cmd == nil {
+			return nil, errors.Wrap(ErrInvalidMessage, "neither cmd nor action given")
+		}
+
+		src := source
+		if in.Src != nil {
+			src = *in.Src
+		}
+
+		return &lcn.LcnPacket{Src: src, Seg: in.Seg, Dst: in.Dst, Cmd: *in.Cmd, Payload: in.Payload}, nil
+	}
+
+	if in.Output < 1 || in.Output > maxOutput {
+		return nil, errors.Wrapf(ErrInvalidMessage, "output %d out of range", in.Output)
+	}
+
+	var action command.RelaisAction
+
+	switch strings.ToLower(in.Action) {
+	case "on":
+		action = command.RelaisOn
+	case "off":
+		action = command.RelaisOff
+	case "toggle":
+		action = command.RelaisToggle
+	default:
+		return nil, errors.Wrapf(ErrInvalidMessage, "unknown action %q", in.Action)
+	}
+
+	segment := byte(segmentOf(int(in.Module)))
+	if in.Segment != nil {
+		segment = *in.Segment
+	}
+
+	cmd := new(command.RelaisCommand)
+	cmd.Outputs[in.Output-1] = action
+
+	pkt, err := command.NewPacket(source, segment, in.Module, cmd)
+
+	return pkt, errors.Wrap(err, "cannot build packet")
+}
+
+// ParseInput reads a frame to send in the current or, if legacy is set, in the legacy format.
+func ParseInput(data []byte, legacy bool, source byte, segmentOf func(module int) int) (*lcn.LcnPacket, error) {
+	if legacy {
+		pkt := new(lcn.LcnPacket)
+		if err := json.Unmarshal(data, pkt); err != nil {
+			return nil, errors.Wrap(ErrInvalidMessage, err.Error())
+		}
+
+		return pkt, nil
+	}
+
+	in := new(Input)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(in); err != nil {
+		return nil, errors.Wrap(ErrInvalidMessage, err.Error())
+	}
+
+	return in.Packet(source, segmentOf)
+}
@@ -0,0 +1,166 @@
+// Package message is the versioned JSON schema of LCN frames published to and read from MQTT.
+package message
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/command"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+// Version of the message schema, messages without version are LcnPacket as JSON (legacy).
+const Version = 1
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Hex are bytes as a hex string in JSON.
+type Hex []byte
+
+func (h Hex) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *Hex) UnmarshalText(text []byte) error {
+	buf, err := hex.DecodeString(string(text))
+	if err != nil {
+		return errors.Wrap(err, "invalid hex")
+	}
+
+	*h = buf
+
+	return nil
+}
+
+// Info are the bits of the INFO byte, all but the length named after unconfirmed guesses, see lcn.InfoFields.
+type Info struct {
+	Length       int  `json:"length"`
+	AckRequested bool `json:"ackRequested"`
+	FromModule   bool `json:"fromModule"`
+	Priority     byte `json:"priority"`
+	Routed       byte `json:"routed"`
+}
+
+// Message is a frame seen on the bus.
+type Message struct {
+	Version   int       `json:"version"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // rx read from the bus, tx written to it
+
+	Src      byte `json:"src"`
+	Seg      byte `json:"seg"`
+	Dst      byte `json:"dst"`
+	Cmd      byte `json:"cmd"`
+	Info     byte `json:"info"`
+	Checksum byte `json:"checksum"`
+	Payload  Hex  `json:"payload"`
+	Invalid  bool `json:"invalid,omitempty"` // checksum did not match
+
+	Command   string          `json:"command"`           // name of the command
+	Text      string          `json:"text,omitempty"`    // decoded command as text
+	Decoded   command.Command `json:"decoded,omitempty"` // fields of known commands
+	InfoFlags Info            `json:"infoFields"`
+}
+
+// New describes a frame, decoding its command if possible.
+func New(t time.Time, dir serial.Direction, pkt *lcn.LcnPacket) *Message {
+	fields := pkt.InfoFields()
+
+	m := &Message{
+		Version:   Version,
+		Time:      t,
+		Direction: dir.String(),
+		Src:       pkt.Src,
+		Seg:       pkt.Seg,
+		Dst:       pkt.Dst,
+		Cmd:       pkt.Cmd,
+		Info:      pkt.Info,
+		Checksum:  pkt.Checksum,
+		Payload:   pkt.Payload,
+		Invalid:   pkt.Invalid,
+		Command:   command.Name(pkt.Cmd),
+		InfoFlags: Info{
+			Length:       fields.Length,
+			AckRequested: fields.AckRequested,
+			FromModule:   fields.FromModule,
+			Priority:     fields.Priority,
+			Routed:       fields.Routed,
+		},
+	}
+
+	if pkt.Invalid {
+		return m
+	}
+
+	if cmd, err := command.Decode(pkt); err == nil {
+		m.Text = cmd.String()
+
+		if command.Known(pkt.Cmd) {
+			m.Decoded = cmd
+		}
+	}
+
+	return m
+}
+
+// Packet returns the frame described by the message.
+func (m *Message) Packet() *lcn.LcnPacket {
+	return &lcn.LcnPacket{
+		Src:      m.Src,
+		Info:     m.Info,
+		Checksum: m.Checksum,
+		Seg:      m.Seg,
+		Dst:      m.Dst,
+		Cmd:      m.Cmd,
+		Payload:  m.Payload,
+		Invalid:  m.Invalid,
+	}
+}
+
+// UnmarshalJSON skips the decoded command, it is derived from cmd and payload.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+
+	var in struct {
+		*plain
+		Decoded json.RawMessage `json:"decoded"`
+	}
+
+	in.plain = (*plain)(m)
+
+	return errors.Wrap(json.Unmarshal(data, &in), "invalid message")
+}
+
+// Parse reads a frame published in either the current or the legacy format.
+func Parse(data []byte) (*lcn.LcnPacket, error) {
+	var version struct {
+		Version int `json:"version"`
+	}
+
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, errors.Wrap(ErrInvalidMessage, err.Error())
+	}
+
+	switch version.Version {
+	case 0:
+		pkt := new(lcn.LcnPacket)
+		if err := json.Unmarshal(data, pkt); err != nil {
+			return nil, errors.Wrap(ErrInvalidMessage, err.Error())
+		}
+
+		return pkt, nil
+	case Version:
+		m := new(Message)
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, errors.Wrap(ErrInvalidMessage, err.Error())
+		}
+
+		return m.Packet(), nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidMessage, "unknown version %d", version.Version)
+	}
+}
@@ -0,0 +1,138 @@
+package message_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/lcn/message"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+)
+
+func TestMessage(t *testing.T) {
+	pkt := &lcn.LcnPacket{Src: 33, Info: 0x06, Checksum: 0x75, Seg: 0, Dst: 4, Cmd: 0x68, Payload: []byte{0x30, 0x01}}
+
+	buf, err := json.Marshal(message.New(time.Date(2023, 3, 5, 18, 21, 7, 0, time.UTC), serial.DirectionRx, pkt))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": 1,
+		"time": "2023-03-05T18:21:07Z",
+		"direction": "rx",
+		"src": 33, "seg": 0, "dst": 4, "cmd": 104, "info": 6, "checksum": 117,
+		"payload": "3001",
+		"command": "statusReport",
+		"text": "relaisStatus 1",
+		"decoded": {"outputs": [true, false, false, false, false, false, false, false]},
+		"infoFields": {"length": 8, "ackRequested": false, "fromModule": true, "priority": 0, "routed": 0}
+	}`, string(buf))
+
+	parsed, err := message.Parse(buf)
+	require.NoError(t, err)
+	assert.Equal(t, pkt, parsed)
+
+	legacy, err := json.Marshal(pkt)
+	require.NoError(t, err)
+
+	parsed, err = message.Parse(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, pkt, parsed)
+
+	_, err = message.Parse([]byte(`{"version":2}`))
+	assert.ErrorIs(t, err, message.ErrInvalidMessage)
+
+	_, err = message.Parse([]byte(`ON`))
+	assert.ErrorIs(t, err, message.ErrInvalidMessage)
+}
+
+func TestMessageInvalid(t *testing.T) {
+	m := message.New(time.Time{}, serial.DirectionTx, &lcn.LcnPacket{Cmd: 0x68, Payload: []byte{0x30, 0x01}, Invalid: true})
+
+	assert.Equal(t, "tx", m.Direction)
+	assert.True(t, m.Invalid)
+	assert.Nil(t, m.Decoded)
+	assert.Empty(t, m.Text)
+}
+
+func TestParseInput(t *testing.T) {
+	segmentOf := func(module int) int {
+		if module == 40 {
+			return 2
+		}
+
+		return 0
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		legacy bool
+		packet *lcn.LcnPacket
+		error  bool
+	}{
+		{
+			name:   "raw",
+			input:  `{"seg":0,"dst":33,"cmd":19,"payload":"0080"}`,
+			packet: &lcn.LcnPacket{Src: 1, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x80}},
+		},
+		{
+			name:   "raw with source",
+			input:  `{"src":7,"seg":0,"dst":33,"cmd":19,"payload":"0080"}`,
+			packet: &lcn.LcnPacket{Src: 7, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x80}},
+		},
+		{
+			name:   "action",
+			input:  `{"module":33,"output":1,"action":"on"}`,
+			packet: &lcn.LcnPacket{Src: 1, Dst: 33, Cmd: 0x13, Payload: []byte{0x01, 0x00}},
+		},
+		{
+			name:   "action in the segment of the module",
+			input:  `{"module":40,"output":8,"action":"TOGGLE"}`,
+			packet: &lcn.LcnPacket{Src: 1, Seg: 2, Dst: 40, Cmd: 0x13, Payload: []byte{0x00, 0x80}},
+		},
+		{
+			name:   "legacy",
+			input:  `{"Src":1,"Seg":0,"Dst":33,"Cmd":19,"Payload":"AIA="}`,
+			legacy: true,
+			packet: &lcn.LcnPacket{Src: 1, Dst: 33, Cmd: 0x13, Payload: []byte{0x00, 0x80}},
+		},
+		{
+			name:  "legacy without switch",
+			input: `{"Src":1,"Seg":0,"Dst":33,"Cmd":19,"Payload":"AIA="}`,
+			error: true,
+		},
+		{
+			name:  "unknown action",
+			input: `{"module":33,"output":1,"action":"dim"}`,
+			error: true,
+		},
+		{
+			name:  "output out of range",
+			input: `{"module":33,"output":9,"action":"on"}`,
+			error: true,
+		},
+		{
+			name:  "nothing to send",
+			input: `{"dst":33}`,
+			error: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pkt, err := message.ParseInput([]byte(tt.input), tt.legacy, 1, segmentOf)
+			if tt.error {
+				assert.ErrorIs(t, err, message.ErrInvalidMessage)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.packet, pkt)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/broker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var _ serial.Observer = &Publisher{}
+
+// Publisher publishes the frames of a serial.Port it observes to <root>/segment/<seg>/target/<dst>/.
+// In the legacy format only received frames are published, as LcnPacket.
+type Publisher struct {
+	broker    broker.Broker
+	rootTopic string
+	legacy    bool
+}
+
+func NewPublisher(b broker.Broker, rootTopic string, legacy bool) *Publisher {
+	return &Publisher{
+		broker:    b,
+		rootTopic: rootTopic,
+		legacy:    legacy,
+	}
+}
+
+func (p *Publisher) Raw(time.Time, serial.Direction, []byte) {}
+
+func (p *Publisher) Frame(t time.Time, dir serial.Direction, pkt packet.Packet) {
+	lcnPacket, ok := pkt.(*lcn.LcnPacket)
+	if !ok {
+		return
+	}
+
+	topic := p.broker.Topic(fmt.Sprintf("%s/segment/%d/target/%d/", p.rootTopic, lcnPacket.Seg, lcnPacket.Dst))
+
+	switch {
+	case !p.legacy:
+		topic.Publish(New(t, dir, lcnPacket))
+	case dir == serial.DirectionRx:
+		topic.Publish(lcnPacket)
+	}
+}
@@ -7,4 +7,5 @@ type Logger struct {
 	DisableStacktrace bool
 	Encoding          string
 	Level             string
+	Output            string // stdout (default), stderr, discard or a file name
 }
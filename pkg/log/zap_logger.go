@@ -1,6 +1,8 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"go.uber.org/zap"
@@ -74,13 +76,33 @@ func (l *logger) InitLogger() {
 	}
 
 	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	core := zapcore.NewCore(encoder, os.Stdout, zap.NewAtomicLevelAt(logLevel))
+	core := zapcore.NewCore(encoder, l.getOutput(), zap.NewAtomicLevelAt(logLevel))
 	//nolint:gomnd
 	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(2))
 
 	l.sugarLogger = logger.Sugar()
 }
 
+func (l *logger) getOutput() zapcore.WriteSyncer {
+	switch l.cfg.Output {
+	case "", "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	case "discard":
+		return zapcore.AddSync(io.Discard)
+	}
+
+	file, err := os.OpenFile(l.cfg.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) //nolint:gomnd
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot open log %s, logging to stdout: %s\n", l.cfg.Output, err)
+
+		return os.Stdout
+	}
+
+	return file
+}
+
 func (l *logger) internalDebug(args ...interface{}) {
 	l.sugarLogger.Debug(args...)
 }
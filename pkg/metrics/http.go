@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+)
+
+const (
+	contentType       = "text/plain; version=0.0.4; charset=utf-8"
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = time.Second
+)
+
+// ServeHTTP makes the registry usable as handler of the metrics path.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+
+	if _, err := r.WriteTo(w); err != nil {
+		log.Debugf("Cannot write metrics: %s", err)
+	}
+}
+
+// Listen serves the metrics on address at /metrics until ctx is done.
+func (r *Registry) Listen(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx) //nolint:errcheck,contextcheck
+	}()
+
+	log.Infof("Serving metrics on %s/metrics", address)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Errorf("Cannot serve metrics: %s", err)
+	}
+}
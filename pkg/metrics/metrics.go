@@ -0,0 +1,192 @@
+// Package metrics exposes counters and gauges in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//nolint:gochecknoglobals
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+)
+
+// Registry holds metric families in the order they were registered.
+type Registry struct {
+	mutex    sync.Mutex
+	families []*family
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mutex   sync.Mutex
+	values  map[string]*sample // keyed by the joined label values
+	collect func() float64     // set for metrics read on every scrape
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	family *family
+}
+
+// Inc adds 1 to the counter with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the counter with the given label values, the number of values must match the labels.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	f := c.family
+
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	s, ok := f.values[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		f.values[key] = s
+	}
+
+	s.value += v
+}
+
+// Counter registers a counter partitioned by labels.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	f := &family{
+		name:   name,
+		help:   help,
+		kind:   typeCounter,
+		labels: labels,
+		values: make(map[string]*sample),
+	}
+
+	r.register(f)
+
+	return &CounterVec{family: f}
+}
+
+// CounterFunc registers a counter read from collect on every scrape.
+func (r *Registry) CounterFunc(name, help string, collect func() float64) {
+	r.register(&family{name: name, help: help, kind: typeCounter, collect: collect})
+}
+
+// GaugeFunc registers a gauge read from collect on every scrape.
+func (r *Registry) GaugeFunc(name, help string, collect func() float64) {
+	r.register(&family{name: name, help: help, kind: typeGauge, collect: collect})
+}
+
+func (r *Registry) register(f *family) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.families {
+		if existing.name == f.name {
+			panic("metric registered twice: " + f.name)
+		}
+	}
+
+	r.families = append(r.families, f)
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	families := append([]*family{}, r.families...)
+	r.mutex.Unlock()
+
+	var b strings.Builder
+
+	for _, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+
+		if f.collect != nil {
+			fmt.Fprintf(&b, "%s %s\n", f.name, formatValue(f.collect()))
+
+			continue
+		}
+
+		for _, s := range f.samples() {
+			fmt.Fprintf(&b, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues), formatValue(s.value))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// samples returns a copy of all samples sorted by label values, so scrapes are stable.
+func (f *family) samples() []sample {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	samples := make([]sample, 0, len(f.values))
+	for _, s := range f.values {
+		samples = append(samples, *s)
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].labelValues, "\xff") < strings.Join(samples[j].labelValues, "\xff")
+	})
+
+	return samples
+}
+
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, label, labelEscaper.Replace(values[i]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
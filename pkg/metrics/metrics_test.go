@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	frames := registry.Counter("lcn_frames_received_total", "Frames received.", "command", "source")
+	registry.CounterFunc("lcn_resyncs_total", "Resyncs.", func() float64 { return 3 })
+	registry.GaugeFunc("lcn_queue_depth", "Frames waiting\nto be sent.", func() float64 { return 0.5 })
+
+	frames.Inc("relais", "21")
+	frames.Inc("relais", "21")
+	frames.Add(4, "0x22", "31")
+	frames.Inc(`a"b`, "1")
+
+	var out strings.Builder
+
+	_, err := registry.WriteTo(&out)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP lcn_frames_received_total Frames received.
+# TYPE lcn_frames_received_total counter
+lcn_frames_received_total{command="0x22",source="31"} 4
+lcn_frames_received_total{command="a\"b",source="1"} 1
+lcn_frames_received_total{command="relais",source="21"} 2
+# HELP lcn_resyncs_total Resyncs.
+# TYPE lcn_resyncs_total counter
+lcn_resyncs_total 3
+# HELP lcn_queue_depth Frames waiting\nto be sent.
+# TYPE lcn_queue_depth gauge
+lcn_queue_depth 0.5
+`, out.String())
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	registry := metrics.NewRegistry()
+	frames := registry.Counter("frames", "", "command")
+
+	assert.Panics(t, func() { frames.Inc() })
+	assert.Panics(t, func() { registry.Counter("frames", "") })
+}
+
+func TestServeHTTP(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.GaugeFunc("up", "", func() float64 { return 1 })
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, recorder.Body.String(), "up 1\n")
+}
@@ -0,0 +1,69 @@
+package pcapng
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/log"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var _ serial.DiscardObserver = &Observer{}
+
+// Observer exports frames and discarded bytes of a port, add it with serial.Observe.
+type Observer struct {
+	*Writer
+}
+
+// Raw is ignored, all received bytes are exported either as frame or as discarded.
+func (o *Observer) Raw(time.Time, serial.Direction, []byte) {}
+
+// Frame exports a frame as received, one accepted despite a wrong checksum is exported as checksum failure.
+func (o *Observer) Frame(t time.Time, dir serial.Direction, pkt packet.Packet) {
+	buf, err := packet.Bytes(pkt)
+	if err != nil {
+		log.Warnf("Cannot export %s: %s", pkt.ToNiceString(), err)
+
+		return
+	}
+
+	kind := KindFrame
+	if flagged, ok := pkt.(packet.Flagged); ok && flagged.ChecksumInvalid() {
+		kind = KindChecksumFailure
+	}
+
+	o.write(t, dir, kind, buf)
+}
+
+// Discard exports a frame candidate with invalid checksum as a whole, otherwise the dropped bytes.
+func (o *Observer) Discard(t time.Time, buf []byte, err error) {
+	switch {
+	case errors.Is(err, packet.ErrPacketChecksum):
+		o.write(t, serial.DirectionRx, KindChecksumFailure, buf)
+
+		return
+	case errors.Is(err, chunker.ErrFrameTimeout):
+		o.write(t, serial.DirectionRx, KindUnframed, buf)
+
+		return
+	}
+
+	o.write(t, serial.DirectionRx, KindUnframed, buf[:1])
+}
+
+func (o *Observer) write(t time.Time, dir serial.Direction, kind Kind, buf []byte) {
+	data := make([]byte, 0, len(buf)+2)
+	data = append(data, byte(kind), byte(dir))
+	data = append(data, buf...)
+
+	if err := o.WritePacket(t, dir == serial.DirectionTx, data); err != nil {
+		log.Debugf("Cannot export to pcapng: %s", err)
+	}
+}
+
+// NewObserver exports to w, which should have been created with LinkTypeUser0.
+func NewObserver(w *Writer) *Observer {
+	return &Observer{Writer: w}
+}
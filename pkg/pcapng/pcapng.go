@@ -0,0 +1,189 @@
+// Package pcapng streams bus traffic into a pcapng file for Wireshark.
+//
+// Packets use the link type USER0 (147). Every packet starts with a pseudo header of two bytes,
+// the Kind and the direction (0 received, 1 sent), followed by the bytes as seen on the bus.
+// The direction is also stored in the epb_flags option.
+package pcapng
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	LinkTypeUser0 = 147
+
+	blockSectionHeader   = 0x0A0D0D0A
+	blockInterface       = 0x00000001
+	blockEnhancedPacket  = 0x00000006
+	byteOrderMagic       = 0x1A2B3C4D
+	optionEnd            = 0
+	optionTimeResolution = 9 // if_tsresol
+	optionFlags          = 2 // epb_flags
+
+	flagInbound  = 0b01
+	flagOutbound = 0b10
+
+	nanoseconds = 9
+)
+
+// Kind tells what a packet contains.
+type Kind byte
+
+const (
+	KindFrame           Kind = iota // a complete frame
+	KindUnframed                    // a byte dropped while searching for a frame
+	KindChecksumFailure             // a frame candidate with invalid checksum
+)
+
+var byteOrder = binary.LittleEndian
+
+// Writer writes a single section with one interface.
+type Writer struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	closer io.Closer
+	err    error
+	failed chan struct{}
+}
+
+// WritePacket writes data captured at t, outbound if sent is set.
+func (w *Writer) WritePacket(t time.Time, sent bool, data []byte) error {
+	flags := uint32(flagInbound)
+	if sent {
+		flags = flagOutbound
+	}
+
+	ts := uint64(t.UnixNano())
+
+	body := make([]byte, 20, 20+padded(len(data))+16)
+	byteOrder.PutUint32(body[0:], 0) // interface ID
+	byteOrder.PutUint32(body[4:], uint32(ts>>32))
+	byteOrder.PutUint32(body[8:], uint32(ts))
+	byteOrder.PutUint32(body[12:], uint32(len(data)))
+	byteOrder.PutUint32(body[16:], uint32(len(data)))
+	body = append(body, pad(data)...)
+	body = appendOption(body, optionFlags, byteOrder.AppendUint32(nil, flags))
+	body = appendOption(body, optionEnd, nil)
+
+	return w.writeBlock(blockEnhancedPacket, body)
+}
+
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	length := uint32(len(body) + 12)
+
+	block := make([]byte, 0, length)
+	block = byteOrder.AppendUint32(block, blockType)
+	block = byteOrder.AppendUint32(block, length)
+	block = append(block, body...)
+	block = byteOrder.AppendUint32(block, length)
+
+	// one write per block, so a reader on a pipe never sees partial blocks
+	if _, err := w.out.Write(block); err != nil {
+		w.err = err
+		close(w.failed)
+
+		return err
+	}
+
+	return nil
+}
+
+// Failed is closed once writing failed, e.g. because the reader of a pipe went away.
+func (w *Writer) Failed() <-chan struct{} {
+	return w.failed
+}
+
+// Err returns the error writing failed with.
+func (w *Writer) Err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.err
+}
+
+func padded(length int) int {
+	return (length + 3) &^ 3
+}
+
+func pad(data []byte) []byte {
+	return append(append([]byte{}, data...), make([]byte, padded(len(data))-len(data))...)
+}
+
+func appendOption(body []byte, code uint16, value []byte) []byte {
+	body = byteOrder.AppendUint16(body, code)
+	body = byteOrder.AppendUint16(body, uint16(len(value)))
+
+	return append(body, pad(value)...)
+}
+
+// NewWriter writes the section header and the interface description to out.
+func NewWriter(out io.Writer, linkType uint16) (*Writer, error) {
+	w := &Writer{
+		out:    out,
+		failed: make(chan struct{}),
+	}
+
+	shb := byteOrder.AppendUint32(nil, byteOrderMagic)
+	shb = byteOrder.AppendUint16(shb, 1)          // major version
+	shb = byteOrder.AppendUint16(shb, 0)          // minor version
+	shb = byteOrder.AppendUint64(shb, ^uint64(0)) // section length unknown
+	shb = appendOption(shb, optionEnd, nil)
+
+	idb := byteOrder.AppendUint16(nil, linkType)
+	idb = byteOrder.AppendUint16(idb, 0) // reserved
+	idb = byteOrder.AppendUint32(idb, 0) // no snap length
+	idb = appendOption(idb, optionTimeResolution, []byte{nanoseconds})
+	idb = appendOption(idb, optionEnd, nil)
+
+	err := errors.Join(
+		w.writeBlock(blockSectionHeader, shb),
+		w.writeBlock(blockInterface, idb),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Create opens filename for writing, it may be a named pipe Wireshark reads from.
+// Opening a pipe blocks until a reader opened it.
+func Create(filename string) (*Writer, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("cannot create pcapng %s: %w", filename, err)
+	}
+
+	w, err := NewWriter(file, LinkTypeUser0)
+	if err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("cannot write pcapng %s: %w", filename, err)
+	}
+
+	w.closer = file
+
+	return w, nil
+}
+
+// Close closes the underlying file, if the writer was created by Create.
+func (w *Writer) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+
+	return w.closer.Close()
+}
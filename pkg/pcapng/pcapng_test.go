@@ -0,0 +1,130 @@
+package pcapng_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/internal/serial/chunker/lcn"
+	"github.com/MyChaOS87/reverseLCN/pkg/pcapng"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/plain"
+)
+
+type block struct {
+	blockType uint32
+	body      []byte
+}
+
+func readBlocks(t *testing.T, buf []byte) []block {
+	t.Helper()
+
+	var blocks []block
+
+	for len(buf) > 0 {
+		require.GreaterOrEqual(t, len(buf), 12)
+
+		length := binary.LittleEndian.Uint32(buf[4:])
+		require.Zero(t, length%4, "blocks are padded to 32 bit")
+		require.LessOrEqual(t, int(length), len(buf))
+		require.Equal(t, length, binary.LittleEndian.Uint32(buf[length-4:]), "trailing length")
+
+		blocks = append(blocks, block{
+			blockType: binary.LittleEndian.Uint32(buf),
+			body:      buf[8 : length-4],
+		})
+
+		buf = buf[length:]
+	}
+
+	return blocks
+}
+
+func TestObserver(t *testing.T) {
+	var out bytes.Buffer
+
+	w, err := pcapng.NewWriter(&out, pcapng.LinkTypeUser0)
+	require.NoError(t, err)
+
+	o := pcapng.NewObserver(w)
+	ts := time.Unix(1678040467, 412000000)
+	frame := plain.Plain{0xa8, 0x06, 0x75}
+
+	o.Frame(ts, serial.DirectionTx, &frame)
+	o.Discard(ts, []byte{0x01, 0x02, 0x03}, packet.ErrPacketChecksum)
+	o.Discard(ts, []byte{0x04, 0x05}, packet.ErrPacketInvalid)
+
+	blocks := readBlocks(t, out.Bytes())
+	require.Len(t, blocks, 5)
+
+	assert.Equal(t, uint32(0x0A0D0D0A), blocks[0].blockType)
+	assert.Equal(t, uint32(0x1A2B3C4D), binary.LittleEndian.Uint32(blocks[0].body))
+
+	assert.Equal(t, uint32(1), blocks[1].blockType)
+	assert.Equal(t, uint16(pcapng.LinkTypeUser0), binary.LittleEndian.Uint16(blocks[1].body))
+
+	tests := []struct {
+		data  []byte
+		flags uint32
+	}{
+		// plain packets serialize to their hex representation
+		{data: append([]byte{byte(pcapng.KindFrame), 1}, "a80675"...), flags: 0b10},
+		{data: []byte{byte(pcapng.KindChecksumFailure), 0, 0x01, 0x02, 0x03}, flags: 0b01},
+		{data: []byte{byte(pcapng.KindUnframed), 0, 0x04}, flags: 0b01},
+	}
+
+	for i, tt := range tests {
+		epb := blocks[i+2]
+		require.Equal(t, uint32(6), epb.blockType)
+
+		timestamp := uint64(binary.LittleEndian.Uint32(epb.body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb.body[8:]))
+		assert.Equal(t, uint64(ts.UnixNano()), timestamp)
+
+		length := int(binary.LittleEndian.Uint32(epb.body[12:]))
+		assert.Equal(t, tt.data, epb.body[20:20+length])
+
+		options := epb.body[20+(length+3)&^3:]
+		assert.Equal(t, uint16(2), binary.LittleEndian.Uint16(options), "epb_flags")
+		assert.Equal(t, tt.flags, binary.LittleEndian.Uint32(options[4:]))
+	}
+}
+
+func TestObserverVerbatim(t *testing.T) {
+	var out bytes.Buffer
+
+	w, err := pcapng.NewWriter(&out, pcapng.LinkTypeUser0)
+	require.NoError(t, err)
+
+	o := pcapng.NewObserver(w)
+	ts := time.Unix(1678040467, 412000000)
+
+	// accepted despite its checksum, it is exported as received
+	frame := []byte{0xa8, 0x06, 0x76, 0x00, 0x04, 0x68, 0x30, 0x00}
+	pkt, err := lcn.NewCodec(lcn.AcceptInvalid(true)).Deserialize(frame)
+	require.NoError(t, err)
+
+	o.Frame(ts, serial.DirectionRx, pkt)
+
+	blocks := readBlocks(t, out.Bytes())
+	require.Len(t, blocks, 3)
+
+	epb := blocks[2]
+	length := int(binary.LittleEndian.Uint32(epb.body[12:]))
+	assert.Equal(t, append([]byte{byte(pcapng.KindChecksumFailure), 0}, frame...), epb.body[20:20+length])
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, assert.AnError
+}
+
+func TestWriterFailed(t *testing.T) {
+	_, err := pcapng.NewWriter(failingWriter{}, pcapng.LinkTypeUser0)
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -3,6 +3,7 @@ package chunker
 import (
 	"bytes"
 	"errors"
+	"time"
 
 	"github.com/MyChaOS87/reverseLCN/pkg/log"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
@@ -12,28 +13,74 @@ var (
 	ErrIncompleteLcn = errors.New("incomplete LCN packet")
 	ErrInvalidLcn    = errors.New("invalid LCN packet")
 	ErrInvalidLcnCRC = errors.New("invalid CRC on LCN packet")
+
+	// ErrFrameTimeout is reported when an incomplete frame is dropped because of MaxGap or MaxAge.
+	ErrFrameTimeout = errors.New("incomplete frame timed out")
 )
 
 type EjectFunc func(packet.Packet)
 
 type Chunker interface {
 	Collect(buf []byte, eject EjectFunc)
+	// Expire drops an incomplete frame if it exceeded MaxGap or MaxAge, call it when nothing was received for a while.
+	Expire()
+	// Buffered returns the number of bytes of a not yet complete packet.
+	Buffered() int
+	// Reset drops all buffered bytes.
+	Reset()
+	Stats() Stats
+}
+
+// Stats are counters of the chunker since it was created.
+type Stats struct {
+	Frames           uint64 // packets ejected
+	DroppedBytes     uint64
+	ChecksumFailures uint64 // frame candidates failing their checksum, rejected or ejected flagged
+	Resyncs          uint64 // times the chunker lost track of frame boundaries
 }
 
 type chunker struct {
 	deserializer packet.Deserializer
 	minLength    int
+	onDiscard    DiscardFunc
 
-	buffer bytes.Buffer
+	maxGap time.Duration
+	maxAge time.Duration
+	clock  func() time.Time
+
+	buffer    bytes.Buffer
+	firstByte time.Time // arrival of the oldest buffered byte
+	lastByte  time.Time
+	synced    bool // the buffer starts at a frame boundary as far as we know
+
+	stats Stats
 }
 
 func (c *chunker) Collect(buf []byte, eject EjectFunc) {
+	if len(buf) == 0 {
+		return
+	}
+
+	now := c.clock()
+
+	c.expire(now)
+
+	if c.buffer.Len() == 0 {
+		c.firstByte = now
+	}
+
+	c.lastByte = now
+
 	reset := func() {
 		c.buffer.Reset()
+		c.firstByte = now
 	}
 
+	// the remaining bytes are a new frame candidate, aged from now as their arrival is not tracked
 	search := func() {
 		c.buffer.Next(1)
+		c.stats.DroppedBytes++
+		c.firstByte = now
 	}
 
 read_loop:
@@ -47,22 +94,96 @@ read_loop:
 				case errors.Is(err, packet.ErrPacketIncomplete):
 					continue read_loop
 				default:
-					log.Errorf("%s 0x%x", err, c.buffer.Bytes())
+					log.Debugf("%s 0x%x", err, c.buffer.Bytes())
+					c.discarded(err)
 					search()
 
-					continue read_loop
+					continue
 				}
 			}
 
+			if flagged, ok := pkt.(packet.Flagged); ok && flagged.ChecksumInvalid() {
+				c.stats.ChecksumFailures++
+			}
+
+			c.stats.Frames++
+			c.synced = true
+
 			eject(pkt)
 			reset()
 		}
 	}
 }
 
-func NewChunker(deserializer packet.Deserializer, minLength int) Chunker {
+func (c *chunker) discarded(err error) {
+	if errors.Is(err, packet.ErrPacketChecksum) {
+		c.stats.ChecksumFailures++
+	}
+
+	if c.synced {
+		c.stats.Resyncs++
+		c.synced = false
+	}
+
+	c.onDiscard(c.buffer.Bytes(), err)
+}
+
+func (c *chunker) Expire() {
+	c.expire(c.clock())
+}
+
+// expire drops the buffer if bytes stopped arriving in the middle of a frame or the frame takes too long.
+// Frames are sent contiguously, so the next byte most likely starts a new frame.
+func (c *chunker) expire(now time.Time) {
+	if c.buffer.Len() == 0 {
+		return
+	}
+
+	gap := c.maxGap > 0 && now.Sub(c.lastByte) > c.maxGap
+	age := c.maxAge > 0 && now.Sub(c.firstByte) > c.maxAge
+
+	if !gap && !age {
+		return
+	}
+
+	log.Debugf("%s 0x%x", ErrFrameTimeout, c.buffer.Bytes())
+
+	c.onDiscard(c.buffer.Bytes(), ErrFrameTimeout)
+
+	c.stats.DroppedBytes += uint64(c.buffer.Len())
+	c.stats.Resyncs++
+	c.synced = true
+
+	c.buffer.Reset()
+}
+
+func (c *chunker) Reset() {
+	c.buffer.Reset()
+	c.synced = true
+}
+
+func (c *chunker) Buffered() int {
+	return c.buffer.Len()
+}
+
+func (c *chunker) Stats() Stats {
+	return c.stats
+}
+
+func NewChunker(deserializer packet.Deserializer, minLength int, options ...Option) Chunker {
+	config := newDefaultConfig()
+
+	for _, opt := range options {
+		opt(config)
+	}
+
 	return &chunker{
 		deserializer: deserializer,
 		minLength:    minLength,
+		onDiscard:    config.onDiscard,
+		maxGap:       config.maxGap,
+		maxAge:       config.maxAge,
+		clock:        config.clock,
+		synced:       true,
 	}
 }
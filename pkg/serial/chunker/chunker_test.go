@@ -3,7 +3,9 @@ package chunker_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
@@ -135,6 +137,13 @@ func TestChunkerCollect(t *testing.T) {
 				onEject(&testPacket{3, 3, 3}, 1),
 			},
 		},
+		{
+			name:    "frame completed before the invalid byte is dropped",
+			buffers: [][]byte{{3, 2, 2}},
+			ejectExpectations: []ejectExpectation{
+				onEject(&testPacket{2, 2}, 1),
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -159,3 +168,186 @@ func TestChunkerCollect(t *testing.T) {
 		})
 	}
 }
+
+func TestChunkerDiscard(t *testing.T) {
+	t.Parallel()
+
+	var discarded [][]byte
+
+	c := chunker.NewChunker(testDeserialize, 2, chunker.OnDiscard(func(buf []byte, err error) {
+		assert.ErrorIs(t, err, packet.ErrPacketInvalid)
+
+		discarded = append(discarded, append([]byte{}, buf...))
+	}))
+
+	e := new(ejectMock)
+	e.On("eject", &testPacket{2, 2}).Once()
+
+	c.Collect([]byte{4, 4, 2, 2}, e.eject)
+
+	e.AssertExpectations(t)
+	assert.Equal(t, [][]byte{{4, 4}, {4, 2}}, discarded)
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestChunkerTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		options   []chunker.Option
+		pause     time.Duration
+		discarded []error
+	}{
+		{
+			name:      "without timeout stale bytes are searched through",
+			pause:     time.Second,
+			discarded: []error{packet.ErrPacketInvalid, packet.ErrPacketInvalid},
+		},
+		{
+			name:      "gap drops stale bytes at once",
+			options:   []chunker.Option{chunker.MaxGap(10 * time.Millisecond)},
+			pause:     20 * time.Millisecond,
+			discarded: []error{chunker.ErrFrameTimeout},
+		},
+		{
+			name:      "short gap keeps bytes",
+			options:   []chunker.Option{chunker.MaxGap(10 * time.Millisecond)},
+			pause:     5 * time.Millisecond,
+			discarded: []error{packet.ErrPacketInvalid, packet.ErrPacketInvalid},
+		},
+		{
+			name:      "age drops stale bytes at once",
+			options:   []chunker.Option{chunker.MaxAge(10 * time.Millisecond)},
+			pause:     20 * time.Millisecond,
+			discarded: []error{chunker.ErrFrameTimeout},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := &testClock{now: time.Unix(0, 0)}
+
+			var discarded []error
+
+			options := append([]chunker.Option{
+				chunker.Clock(clock.Now),
+				chunker.OnDiscard(func(_ []byte, err error) {
+					discarded = append(discarded, err)
+				}),
+			}, tt.options...)
+
+			c := chunker.NewChunker(testDeserialize, 2, options...)
+
+			var ejected []packet.Packet
+
+			// the frame {3, 3, 3} stops after two bytes, the frame {2, 2} follows after a pause
+			c.Collect([]byte{3, 3}, func(pkt packet.Packet) { ejected = append(ejected, pkt) })
+			clock.Advance(tt.pause)
+			c.Collect([]byte{2, 2}, func(pkt packet.Packet) { ejected = append(ejected, pkt) })
+
+			assert.Equal(t, []packet.Packet{&testPacket{2, 2}}, ejected)
+			assert.Equal(t, chunker.Stats{Frames: 1, DroppedBytes: 2, Resyncs: 1}, c.Stats())
+			assert.Equal(t, tt.discarded, discarded)
+		})
+	}
+}
+
+func TestChunkerAgeAfterResync(t *testing.T) {
+	t.Parallel()
+
+	clock := &testClock{now: time.Unix(0, 0)}
+	c := chunker.NewChunker(testDeserialize, 2, chunker.Clock(clock.Now), chunker.MaxAge(10*time.Millisecond))
+
+	var ejected []packet.Packet
+
+	// {3, 3} is broken by the 2 arriving later, the frame {2, 2} starts with it
+	c.Collect([]byte{3, 3}, func(pkt packet.Packet) { ejected = append(ejected, pkt) })
+	clock.Advance(5 * time.Millisecond)
+	c.Collect([]byte{2}, func(pkt packet.Packet) { ejected = append(ejected, pkt) })
+	clock.Advance(8 * time.Millisecond)
+	c.Collect([]byte{2}, func(pkt packet.Packet) { ejected = append(ejected, pkt) })
+
+	assert.Equal(t, []packet.Packet{&testPacket{2, 2}}, ejected)
+}
+
+func TestChunkerExpire(t *testing.T) {
+	t.Parallel()
+
+	clock := &testClock{now: time.Unix(0, 0)}
+	c := chunker.NewChunker(testDeserialize, 2, chunker.Clock(clock.Now), chunker.MaxGap(10*time.Millisecond))
+
+	c.Collect([]byte{3, 3}, func(packet.Packet) {})
+	assert.Equal(t, 2, c.Buffered())
+
+	c.Expire()
+	assert.Equal(t, 2, c.Buffered())
+
+	clock.Advance(20 * time.Millisecond)
+	c.Expire()
+	assert.Zero(t, c.Buffered())
+	assert.Equal(t, chunker.Stats{DroppedBytes: 2, Resyncs: 1}, c.Stats())
+}
+
+func TestChunkerStats(t *testing.T) {
+	t.Parallel()
+
+	c := chunker.NewChunker(func(buf []byte) (packet.Packet, error) {
+		if buf[0] == 0xff {
+			return nil, packet.ErrPacketChecksum
+		}
+
+		return testDeserialize(buf)
+	}, 2)
+
+	c.Collect([]byte{2, 2, 0xff, 7, 2, 2, 0xff, 2, 2}, func(packet.Packet) {})
+
+	assert.Equal(t, chunker.Stats{
+		Frames:           3,
+		DroppedBytes:     3,
+		ChecksumFailures: 2,
+		Resyncs:          2,
+	}, c.Stats())
+}
+
+type flaggedPacket struct {
+	testPacket
+}
+
+func (*flaggedPacket) ChecksumInvalid() bool {
+	return true
+}
+
+func TestChunkerStatsFlagged(t *testing.T) {
+	t.Parallel()
+
+	// frames starting with 3 fail their checksum but are accepted
+	c := chunker.NewChunker(func(buf []byte) (packet.Packet, error) {
+		pkt, err := testDeserialize(buf)
+		if err == nil && buf[0] == 3 {
+			return &flaggedPacket{testPacket: *pkt.(*testPacket)}, nil
+		}
+
+		return pkt, err
+	}, 2)
+
+	ejected := 0
+
+	c.Collect([]byte{2, 2, 3, 3, 3, 2, 2}, func(packet.Packet) { ejected++ })
+
+	assert.Equal(t, 3, ejected)
+	assert.Equal(t, chunker.Stats{Frames: 3, ChecksumFailures: 1}, c.Stats())
+}
@@ -0,0 +1,52 @@
+package chunker
+
+import "time"
+
+type (
+	Option func(*Config)
+	Config struct {
+		onDiscard DiscardFunc
+		maxGap    time.Duration
+		maxAge    time.Duration
+		clock     func() time.Time
+	}
+)
+
+// DiscardFunc is called with a buffer the deserializer rejected and the reason, the chunker then drops its first byte.
+// For ErrFrameTimeout the whole buffer is dropped. buf is only valid during the call.
+type DiscardFunc func(buf []byte, err error)
+
+// OnDiscard sets a callback seeing every rejected buffer, e.g. to export it.
+func OnDiscard(onDiscard DiscardFunc) Option {
+	return func(c *Config) {
+		c.onDiscard = onDiscard
+	}
+}
+
+// MaxGap drops an incomplete frame if no byte was received for longer than gap, 0 disables it.
+func MaxGap(gap time.Duration) Option {
+	return func(c *Config) {
+		c.maxGap = gap
+	}
+}
+
+// MaxAge drops an incomplete frame if its first byte was received longer than age ago, 0 disables it.
+func MaxAge(age time.Duration) Option {
+	return func(c *Config) {
+		c.maxAge = age
+	}
+}
+
+// Clock replaces time.Now, e.g. to replay recorded timestamps or in tests.
+func Clock(clock func() time.Time) Option {
+	return func(c *Config) {
+		c.clock = clock
+	}
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		onDiscard: func([]byte, error) {},
+		clock:     time.Now,
+	}
+}
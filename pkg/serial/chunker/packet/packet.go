@@ -1,10 +1,14 @@
 package packet
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrPacketIncomplete = errors.New("Packet Incomplete")
 	ErrPacketInvalid    = errors.New("Packet Invalid")
+	ErrPacketChecksum   = fmt.Errorf("%w: Checksum invalid", ErrPacketInvalid)
 )
 
 type Packet interface {
@@ -14,3 +18,22 @@ type Packet interface {
 }
 
 type Deserializer func(buf []byte) (Packet, error)
+
+// Flagged is implemented by packets a deserializer may accept despite a wrong checksum.
+type Flagged interface {
+	ChecksumInvalid() bool
+}
+
+// Verbatim is implemented by packets reproducing the bytes they were deserialized from, wrong checksum included.
+type Verbatim interface {
+	Verbatim() ([]byte, error)
+}
+
+// Bytes returns a packet as seen on the bus, packets not implementing Verbatim are serialized.
+func Bytes(pkt Packet) ([]byte, error) {
+	if v, ok := pkt.(Verbatim); ok {
+		return v.Verbatim()
+	}
+
+	return pkt.Serialize()
+}
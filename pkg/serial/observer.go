@@ -0,0 +1,73 @@
+package serial
+
+import (
+	"time"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+// Direction tells whether bytes were received from or sent to the bus.
+type Direction int
+
+const (
+	DirectionRx Direction = iota
+	DirectionTx
+)
+
+// Observer sees all traffic of a port, e.g. to record it.
+type Observer interface {
+	// Raw is called with every chunk of bytes read from or written to the device.
+	Raw(t time.Time, dir Direction, buf []byte)
+	// Frame is called with every packet received or sent.
+	Frame(t time.Time, dir Direction, pkt packet.Packet)
+}
+
+// DiscardObserver is an Observer also seeing received bytes the chunker rejected.
+type DiscardObserver interface {
+	Observer
+	// Discard is called with a rejected buffer, only its first byte is dropped.
+	Discard(t time.Time, buf []byte, err error)
+}
+
+func (d Direction) String() string {
+	if d == DirectionTx {
+		return "tx"
+	}
+
+	return "rx"
+}
+
+func (p *port) observeRaw(t time.Time, dir Direction, buf []byte) {
+	for _, o := range p.observers {
+		o.Raw(t, dir, buf)
+	}
+}
+
+func (p *port) observeFrame(t time.Time, dir Direction, pkt packet.Packet) {
+	for _, o := range p.observers {
+		o.Frame(t, dir, pkt)
+	}
+}
+
+func (p *port) observeDiscard(buf []byte, err error) {
+	now := time.Now()
+
+	for _, o := range p.observers {
+		if d, ok := o.(DiscardObserver); ok {
+			d.Discard(now, buf, err)
+		}
+	}
+}
+
+// observeSent reports a written frame, as far as it can be deserialized.
+func (p *port) observeSent(t time.Time, buf []byte) {
+	if len(p.observers) == 0 {
+		return
+	}
+
+	p.observeRaw(t, DirectionTx, buf)
+
+	if pkt, err := p.deserializer(buf); err == nil {
+		p.observeFrame(t, DirectionTx, pkt)
+	}
+}
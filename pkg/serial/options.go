@@ -1,6 +1,8 @@
 package serial
 
 import (
+	"time"
+
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/plain"
 	"go.bug.st/serial"
@@ -10,21 +12,48 @@ type (
 	Option func(*Config)
 	Config struct {
 		portName     string
+		transport    Transport
 		baudRate     int
 		parity       serial.Parity
 		dataBits     int
 		stopBits     serial.StopBits
 		deserializer packet.Deserializer
 		minLength    int
+
+		requestTimeout time.Duration
+		requestRetries int
+		retryBackoff   time.Duration
+
+		busIdle      time.Duration
+		frameSpacing time.Duration
+		maxRate      int
+		queueSize    int
+
+		maxGap      time.Duration
+		maxFrameAge time.Duration
+
+		reconnectMin     time.Duration
+		reconnectMax     time.Duration
+		failOnDisconnect bool
+
+		observers []Observer
 	}
 )
 
+// PortName sets the address of the bus, see NewTransport.
 func PortName(portName string) Option {
 	return func(c *Config) {
 		c.portName = portName
 	}
 }
 
+// UseTransport sets how the bus is reached, overriding PortName and the serial mode.
+func UseTransport(transport Transport) Option {
+	return func(c *Config) {
+		c.transport = transport
+	}
+}
+
 func BaudRate(baudRate int) Option {
 	return func(c *Config) {
 		c.baudRate = baudRate
@@ -61,6 +90,91 @@ func MinLength(minLength int) Option {
 	}
 }
 
+// RequestTimeout sets how long Request waits for a response before retrying.
+func RequestTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.requestTimeout = timeout
+	}
+}
+
+// RequestRetries sets how often Request resends before giving up.
+func RequestRetries(retries int) Option {
+	return func(c *Config) {
+		c.requestRetries = retries
+	}
+}
+
+// RetryBackoff sets the pause before the first retry of a Request, it doubles with every retry.
+func RetryBackoff(backoff time.Duration) Option {
+	return func(c *Config) {
+		c.retryBackoff = backoff
+	}
+}
+
+// BusIdle sets how long no byte must have been received before a frame is sent.
+func BusIdle(idle time.Duration) Option {
+	return func(c *Config) {
+		c.busIdle = idle
+	}
+}
+
+// FrameSpacing sets the minimum time between the start of two sent frames.
+func FrameSpacing(spacing time.Duration) Option {
+	return func(c *Config) {
+		c.frameSpacing = spacing
+	}
+}
+
+// MaxRate limits the number of frames sent per second, 0 disables the limit.
+func MaxRate(framesPerSecond int) Option {
+	return func(c *Config) {
+		c.maxRate = framesPerSecond
+	}
+}
+
+// MaxGap sets how long a pause within a frame may be before the incomplete frame is dropped, 0 disables it.
+func MaxGap(gap time.Duration) Option {
+	return func(c *Config) {
+		c.maxGap = gap
+	}
+}
+
+// MaxFrameAge sets how long receiving a frame may take before the incomplete frame is dropped, 0 disables it.
+func MaxFrameAge(age time.Duration) Option {
+	return func(c *Config) {
+		c.maxFrameAge = age
+	}
+}
+
+// QueueSize sets how many frames may wait to be sent before Send blocks.
+func QueueSize(size int) Option {
+	return func(c *Config) {
+		c.queueSize = size
+	}
+}
+
+// ReconnectBackoff sets the first and the longest pause between attempts to reopen the device.
+func ReconnectBackoff(first, longest time.Duration) Option {
+	return func(c *Config) {
+		c.reconnectMin = first
+		c.reconnectMax = longest
+	}
+}
+
+// FailOnDisconnect makes Send fail with ErrDisconnected while the device is not open, instead of waiting for it.
+func FailOnDisconnect(fail bool) Option {
+	return func(c *Config) {
+		c.failOnDisconnect = fail
+	}
+}
+
+// Observe adds an observer seeing all received and sent bytes and frames.
+func Observe(observer Observer) Option {
+	return func(c *Config) {
+		c.observers = append(c.observers, observer)
+	}
+}
+
 func newDefaultConfig() *Config {
 	return &Config{
 		portName:     "/dev/ttyACM0",
@@ -70,5 +184,20 @@ func newDefaultConfig() *Config {
 		stopBits:     serial.OneStopBit,
 		deserializer: plain.Deserialize,
 		minLength:    1,
+
+		requestTimeout: 500 * time.Millisecond,
+		requestRetries: 3,
+		retryBackoff:   200 * time.Millisecond,
+
+		busIdle:      10 * time.Millisecond,
+		frameSpacing: 20 * time.Millisecond,
+		maxRate:      20,
+		queueSize:    64,
+
+		maxGap:      50 * time.Millisecond,
+		maxFrameAge: 200 * time.Millisecond,
+
+		reconnectMin: 500 * time.Millisecond,
+		reconnectMax: 30 * time.Second,
 	}
 }
@@ -2,84 +2,471 @@ package serial
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.bug.st/serial"
 
 	"github.com/MyChaOS87/reverseLCN/pkg/log"
 	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
 )
 
-const bufferSize = 1024
+const (
+	bufferSize  = 1024
+	readTimeout = 100 * time.Millisecond
+
+	// maxFrameLength is the longest LCN frame in bytes, used to estimate when a partial frame got stuck
+	maxFrameLength = 20
+	bitsPerByte    = 10
+)
+
+var (
+	ErrWriteFailed  = errors.New("write to serial failed")
+	ErrNoResponse   = errors.New("no response received")
+	ErrDisconnected = errors.New("serial device disconnected")
+)
 
 type Port interface {
+	// Run reads and writes until ctx is done, cancel is called if the bus can never be reached.
 	Run(ctx context.Context, cancel context.CancelFunc, eject chunker.EjectFunc)
-	Send(buf []byte)
+	// Send queues buf and blocks until it was written or ctx is done, once ctx is done buf is not written anymore.
+	Send(ctx context.Context, buf []byte) error
+	// Request sends buf until a packet accepted by expect is received, retrying with backoff.
+	Request(ctx context.Context, buf []byte, expect Matcher) (packet.Packet, error)
+	// QueueDepth returns the number of packets waiting to be sent.
+	QueueDepth() int
+	State() State
+	// OnStateChange registers a callback for connection state changes, it is called immediately with the current state.
+	OnStateChange(callback StateCallback)
+	Stats() Stats
 }
 
-type port struct {
-	sendQueue chan []byte
+// Matcher reports whether a received packet is the response to a request.
+type Matcher func(packet.Packet) bool
 
-	portName string
-	mode     serial.Mode
-	chunker  chunker.Chunker
+type sendRequest struct {
+	done   <-chan struct{} // of the sender, the request is dropped once it gave up
+	buf    []byte
+	result chan error
 }
 
-func (p *port) Send(buf []byte) {
-	p.sendQueue <- buf
+type waiter struct {
+	expect   Matcher
+	response chan packet.Packet
 }
 
-func (p *port) Run(ctx context.Context, cancel context.CancelFunc, eject chunker.EjectFunc) {
-	port, err := serial.Open(p.portName, &p.mode)
-	if err != nil {
-		log.Errorf("Cannot Open Port %s: %s", p.portName, err.Error())
-		cancel()
-		return
+type port struct {
+	sendQueue chan sendRequest
+
+	portName     string
+	transport    Transport
+	chunker      chunker.Chunker
+	deserializer packet.Deserializer
+	observers    []Observer
+
+	requestTimeout time.Duration
+	requestRetries int
+	retryBackoff   time.Duration
+
+	busIdle      time.Duration
+	frameSpacing time.Duration
+	frameTimeout time.Duration
+	maxRate      int
+
+	mutex   sync.Mutex
+	waiters map[*waiter]struct{}
+
+	reconnectMin     time.Duration
+	reconnectMax     time.Duration
+	failOnDisconnect bool
+
+	connMutex   sync.Mutex
+	conn        Conn
+	connChanged chan struct{}
+
+	stateMutex     sync.Mutex
+	state          State
+	stateCallbacks []StateCallback
+
+	stats stats
+
+	rxMutex sync.Mutex
+	lastRx  atomic.Int64 // unix nanos of the last received byte
+	lastTx  time.Time
+	txTimes []time.Time // transmits during the last second
+}
+
+func (p *port) Send(ctx context.Context, buf []byte) error {
+	request := sendRequest{
+		done:   ctx.Done(),
+		buf:    buf,
+		result: make(chan error, 1),
 	}
 
-	// ensure that we try to read twice per bufferSize using the baudRate, thus we should never read to slow to catch everything given enough resources
-	// ticker := time.NewTicker((bufferSize * time.Second) / time.Duration(r.mode.BaudRate*2))
-	ticker := time.NewTicker(8 * time.Millisecond)
+	select {
+	case p.sendQueue <- request:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	err = port.SetReadTimeout(100 * time.Millisecond)
-	if err != nil {
-		log.Errorf("Cannot set read timeout on serial(%s): %s", p.portName, err.Error())
-		cancel()
-		return
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	go func() {
-		defer port.Close()
-		defer ticker.Stop()
+func (p *port) Request(ctx context.Context, buf []byte, expect Matcher) (packet.Packet, error) {
+	w := &waiter{
+		expect:   expect,
+		response: make(chan packet.Packet, 1),
+	}
+
+	p.mutex.Lock()
+	p.waiters[w] = struct{}{}
+	p.mutex.Unlock()
+
+	defer func() {
+		p.mutex.Lock()
+		delete(p.waiters, w)
+		p.mutex.Unlock()
+	}()
+
+	backoff := p.retryBackoff
+
+	for attempt := 0; attempt <= p.requestRetries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("No response to %x on serial(%s), retry %d/%d", buf, p.portName, attempt, p.requestRetries)
 
-		for {
 			select {
-			case message := <-p.sendQueue:
-				length, err := port.Write(message)
-				if err != nil {
-					log.Errorf("Error writing %v to serial(%s): %s", message, p.portName, err.Error())
-				} else if length != len(message) {
-					log.Errorf("Incomplete write of %v to serial(%s): sent %d", message, p.portName, length)
-				} else {
-					log.Debugf("Wrote %v to serial(%s): ", message, p.portName)
-				}
-			case <-ticker.C:
-				buffer := make([]byte, bufferSize)
-
-				len, err := port.Read(buffer)
-				if err != nil {
-					log.Errorf("Error reading from serial(%s): %s", p.portName, err.Error())
-					cancel()
-					return
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			backoff *= 2
+		}
+
+		if err := p.Send(ctx, buf); err != nil {
+			return nil, err
+		}
+
+		timeout := time.NewTimer(p.requestTimeout)
+
+		select {
+		case pkt := <-w.response:
+			timeout.Stop()
+
+			return pkt, nil
+		case <-timeout.C:
+		case <-ctx.Done():
+			timeout.Stop()
+
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, errors.Wrapf(ErrNoResponse, "after %d attempts", p.requestRetries+1)
+}
+
+// dispatch hands a received packet to all matching requests before ejecting it.
+func (p *port) dispatch(eject chunker.EjectFunc) chunker.EjectFunc {
+	return func(pkt packet.Packet) {
+		p.stats.received.Add(1)
+		p.observeFrame(time.Now(), DirectionRx, pkt)
+
+		p.mutex.Lock()
+		for w := range p.waiters {
+			if w.expect(pkt) {
+				select {
+				case w.response <- pkt:
+				default:
 				}
+			}
+		}
+		p.mutex.Unlock()
+
+		eject(pkt)
+	}
+}
+
+func (p *port) write(conn Conn, message []byte) error {
+	length, err := conn.Write(message)
+	if err != nil {
+		log.Errorf("Error writing %v to serial(%s): %s", message, p.portName, err.Error())
+
+		return errors.Wrap(ErrWriteFailed, err.Error())
+	}
+
+	if length != len(message) {
+		log.Errorf("Incomplete write of %v to serial(%s): sent %d", message, p.portName, length)
+
+		return errors.Wrapf(ErrWriteFailed, "incomplete write of %d/%d bytes", length, len(message))
+	}
+
+	log.Debugf("Wrote %v to serial(%s): ", message, p.portName)
+
+	return nil
+}
+
+func (p *port) Run(ctx context.Context, cancel context.CancelFunc, eject chunker.EjectFunc) {
+	eject = p.dispatch(eject)
+
+	go p.writeLoop(ctx)
+	go p.connectLoop(ctx, cancel, eject)
+}
+
+// connectLoop (re-)opens the device with exponential backoff until ctx is done, an invalid address cancels.
+func (p *port) connectLoop(ctx context.Context, cancel context.CancelFunc, eject chunker.EjectFunc) {
+	backoff := p.reconnectMin
 
-				p.chunker.Collect(buffer[0:len], eject)
+	for {
+		p.setState(StateConnecting)
+
+		conn, err := p.transport.Open()
+		if errors.Is(err, ErrInvalidAddress) {
+			log.Errorf("Cannot open serial(%s): %s", p.portName, err)
+			p.setState(StateDisconnected)
+			cancel()
+
+			return
+		}
+
+		if err != nil {
+			log.Errorf("%s, retrying in %s", err, backoff)
+			p.setState(StateDisconnected)
+
+			select {
+			case <-time.After(backoff):
 			case <-ctx.Done():
-				log.Errorf("Context done: %s", ctx.Err())
 				return
 			}
+
+			backoff = min(2*backoff, p.reconnectMax)
+
+			continue
 		}
-	}()
+
+		backoff = p.reconnectMin
+
+		p.stats.connects.Add(1)
+
+		p.rxMutex.Lock()
+		p.chunker.Reset()
+		p.rxMutex.Unlock()
+
+		p.setConnection(conn)
+		p.setState(StateConnected)
+
+		p.readLoop(ctx, conn, eject)
+
+		p.setConnection(nil)
+		conn.Close()
+
+		if ctx.Err() != nil {
+			log.Errorf("Context done: %s", ctx.Err())
+			p.setState(StateDisconnected)
+
+			return
+		}
+
+		p.setState(StateDisconnected)
+	}
+}
+
+// readLoop reads until reading fails or ctx is done. Received packets are ejected after the chunker is
+// unlocked, so a slow consumer does not hold up sending.
+func (p *port) readLoop(ctx context.Context, conn Conn, eject chunker.EjectFunc) {
+	buffer := make([]byte, bufferSize)
+
+	var received []packet.Packet
+
+	collect := func(pkt packet.Packet) {
+		received = append(received, pkt)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		len, err := conn.Read(buffer)
+		if err != nil {
+			log.Errorf("Error reading from serial(%s): %s", p.portName, err.Error())
+			return
+		}
+
+		if len == 0 {
+			p.rxMutex.Lock()
+			p.chunker.Expire()
+			p.rxMutex.Unlock()
+
+			continue
+		}
+
+		now := time.Now()
+		p.lastRx.Store(now.UnixNano())
+		p.observeRaw(now, DirectionRx, buffer[0:len])
+
+		p.rxMutex.Lock()
+		p.chunker.Collect(buffer[0:len], collect)
+		p.rxMutex.Unlock()
+
+		for _, pkt := range received {
+			eject(pkt)
+		}
+
+		received = received[:0]
+	}
+}
+
+func (p *port) setConnection(conn Conn) {
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+
+	p.conn = conn
+
+	close(p.connChanged)
+	p.connChanged = make(chan struct{})
+}
+
+// awaitConnection returns the open device, waiting for it unless queued sends fail while disconnected.
+func (p *port) awaitConnection(ctx context.Context) (Conn, error) {
+	for {
+		p.connMutex.Lock()
+		conn, changed := p.conn, p.connChanged
+		p.connMutex.Unlock()
+
+		if conn != nil {
+			return conn, nil
+		}
+
+		if p.failOnDisconnect {
+			return nil, ErrDisconnected
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (p *port) writeLoop(ctx context.Context) {
+	for {
+		select {
+		case request := <-p.sendQueue:
+			conn, err := p.awaitConnection(ctx)
+			if err != nil {
+				request.result <- err
+
+				continue
+			}
+
+			if err := p.awaitTransmitWindow(ctx); err != nil {
+				request.result <- err
+
+				continue
+			}
+
+			// the sender gave up while the request was queued
+			select {
+			case <-request.done:
+				request.result <- context.Canceled
+
+				continue
+			default:
+			}
+
+			err = p.write(conn, request.buf)
+			if err != nil {
+				p.stats.writeErrors.Add(1)
+			} else {
+				p.stats.sent.Add(1)
+				p.observeSent(time.Now(), request.buf)
+			}
+
+			request.result <- err
+
+			p.recordTransmit(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// awaitTransmitWindow blocks until the bus is idle and neither frame spacing nor rate limit forbid sending.
+func (p *port) awaitTransmitWindow(ctx context.Context) error {
+	for {
+		wait := p.transmitDelay(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *port) transmitDelay(now time.Time) time.Duration {
+	idle := p.busIdle
+
+	// a partially received frame keeps the bus busy, unless it got stuck
+	p.rxMutex.Lock()
+	receiving := p.chunker.Buffered() > 0
+	p.rxMutex.Unlock()
+
+	if receiving && p.frameTimeout > idle {
+		idle = p.frameTimeout
+	}
+
+	wait := time.Unix(0, p.lastRx.Load()).Add(idle).Sub(now)
+	wait = max(wait, p.lastTx.Add(p.frameSpacing).Sub(now))
+
+	for len(p.txTimes) > 0 && now.Sub(p.txTimes[0]) >= time.Second {
+		p.txTimes = p.txTimes[1:]
+	}
+
+	if p.maxRate > 0 && len(p.txTimes) >= p.maxRate {
+		wait = max(wait, p.txTimes[0].Add(time.Second).Sub(now))
+	}
+
+	return wait
+}
+
+func (p *port) recordTransmit(now time.Time) {
+	p.lastTx = now
+
+	if p.maxRate > 0 {
+		p.txTimes = append(p.txTimes, now)
+	}
+}
+
+func (p *port) QueueDepth() int {
+	return len(p.sendQueue)
+}
+
+// frameTime returns the time it takes to transmit the longest frame at the given baud rate.
+func frameTime(baudRate int) time.Duration {
+	if baudRate <= 0 {
+		return 0
+	}
+
+	return time.Duration(maxFrameLength*bitsPerByte) * time.Second / time.Duration(baudRate)
 }
 
 func NewPort(options ...Option) Port {
@@ -89,16 +476,51 @@ func NewPort(options ...Option) Port {
 		opt(config)
 	}
 
-	return &port{
-		portName: config.portName,
-		mode: serial.Mode{
+	transport := config.transport
+	if transport == nil {
+		var err error
+
+		transport, err = NewTransport(config.portName, serial.Mode{
 			BaudRate: config.baudRate,
 			Parity:   config.parity,
 			DataBits: config.dataBits,
 			StopBits: config.stopBits,
-		},
-		chunker: chunker.NewChunker(config.deserializer, config.minLength),
+		})
+		if err != nil {
+			log.Errorf("Cannot use port %s: %s", config.portName, err)
+
+			transport = &failedTransport{address: config.portName, err: err}
+		}
+	}
+
+	p := &port{
+		portName:     transport.String(),
+		transport:    transport,
+		deserializer: config.deserializer,
+		observers:    config.observers,
 
-		sendQueue: make(chan []byte, 10),
+		requestTimeout: config.requestTimeout,
+		requestRetries: config.requestRetries,
+		retryBackoff:   config.retryBackoff,
+
+		busIdle:      config.busIdle,
+		frameSpacing: config.frameSpacing,
+		frameTimeout: frameTime(config.baudRate),
+		maxRate:      config.maxRate,
+
+		reconnectMin:     config.reconnectMin,
+		reconnectMax:     config.reconnectMax,
+		failOnDisconnect: config.failOnDisconnect,
+		connChanged:      make(chan struct{}),
+
+		sendQueue: make(chan sendRequest, config.queueSize),
+		waiters:   make(map[*waiter]struct{}),
 	}
+
+	p.chunker = chunker.NewChunker(config.deserializer, config.minLength,
+		chunker.OnDiscard(p.observeDiscard),
+		chunker.MaxGap(config.maxGap),
+		chunker.MaxAge(config.maxFrameAge))
+
+	return p
 }
@@ -0,0 +1,419 @@
+package serial_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+)
+
+var errUnplugged = errors.New("unplugged")
+
+// fakeConn is an open bus, bytes put into rx are received by the port and written bytes show up on written.
+type fakeConn struct {
+	rx      chan []byte
+	written chan written
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type written struct {
+	at  time.Time
+	buf []byte
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		rx:      make(chan []byte, 10),
+		written: make(chan written, 100),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *fakeConn) Read(buf []byte) (int, error) {
+	select {
+	case data := <-c.rx:
+		return copy(buf, data), nil
+	case <-c.closed:
+		return 0, io.EOF
+	case <-time.After(5 * time.Millisecond):
+		return 0, nil
+	}
+}
+
+func (c *fakeConn) Write(buf []byte) (int, error) {
+	c.written <- written{at: time.Now(), buf: append([]byte{}, buf...)}
+
+	return len(buf), nil
+}
+
+func (c *fakeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+
+	return nil
+}
+
+// next returns the next written frame.
+func (c *fakeConn) next(t *testing.T) written {
+	t.Helper()
+
+	select {
+	case w := <-c.written:
+		return w
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "nothing written")
+
+		return written{}
+	}
+}
+
+// fakeTransport hands out the connections put into conns, opening fails while there is none.
+type fakeTransport struct {
+	conns    chan *fakeConn
+	err      error
+	attempts chan time.Time
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{
+		conns:    make(chan *fakeConn, 10),
+		err:      errUnplugged,
+		attempts: make(chan time.Time, 100),
+	}
+}
+
+func (t *fakeTransport) Open() (serial.Conn, error) {
+	t.attempts <- time.Now()
+
+	select {
+	case conn := <-t.conns:
+		return conn, nil
+	default:
+		return nil, t.err
+	}
+}
+
+func (t *fakeTransport) String() string {
+	return "fake"
+}
+
+// newPort runs a port on transport without pacing, options override that.
+func newPort(
+	ctx context.Context, cancel context.CancelFunc, transport serial.Transport, eject func(packet.Packet),
+	options ...serial.Option,
+) serial.Port {
+	options = append([]serial.Option{
+		serial.UseTransport(transport),
+		serial.BusIdle(0),
+		serial.FrameSpacing(0),
+		serial.MaxRate(0),
+		serial.ReconnectBackoff(time.Millisecond, time.Millisecond),
+	}, options...)
+
+	port := serial.NewPort(options...)
+	port.Run(ctx, cancel, eject)
+
+	return port
+}
+
+func TestSendCancelledWhileQueued(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {})
+
+	// queued while disconnected, the sender gives up before the device is back
+	sendCtx, sendCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer sendCancel()
+
+	require.ErrorIs(t, port.Send(sendCtx, []byte{0x01}), context.DeadlineExceeded)
+
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	require.NoError(t, port.Send(ctx, []byte{0x02}))
+	assert.Equal(t, []byte{0x02}, conn.next(t).buf, "the abandoned frame is not written")
+}
+
+func TestSlowConsumerDoesNotBlockSending(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {
+		close(entered)
+		<-release
+	}, serial.BusIdle(time.Millisecond))
+	defer close(release)
+
+	conn.rx <- []byte{0x42}
+	<-entered
+
+	sendCtx, sendCancel := context.WithTimeout(ctx, time.Second)
+	defer sendCancel()
+
+	require.NoError(t, port.Send(sendCtx, []byte{0x01}))
+	assert.Equal(t, []byte{0x01}, conn.next(t).buf)
+}
+
+func TestInvalidAddressCancels(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	transport.err = errors.Wrap(serial.ErrInvalidAddress, "no device")
+
+	newPort(ctx, cancel, transport, func(packet.Packet) {})
+
+	<-ctx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Len(t, transport.attempts, 1, "an invalid address is not retried")
+}
+
+func TestBusIdle(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const idle = 50 * time.Millisecond
+
+	received := make(chan time.Time, 1)
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {
+		received <- time.Now()
+	}, serial.BusIdle(idle))
+
+	conn.rx <- []byte{0x42}
+	rx := <-received
+
+	require.NoError(t, port.Send(ctx, []byte{0x01}))
+	assert.GreaterOrEqual(t, conn.next(t).at.Sub(rx), idle-5*time.Millisecond,
+		"the bus stays idle after a received byte")
+}
+
+func TestFrameSpacing(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const spacing = 30 * time.Millisecond
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {}, serial.FrameSpacing(spacing))
+
+	require.NoError(t, port.Send(ctx, []byte{0x01}))
+	require.NoError(t, port.Send(ctx, []byte{0x02}))
+
+	first, second := conn.next(t), conn.next(t)
+	assert.GreaterOrEqual(t, second.at.Sub(first.at), spacing-5*time.Millisecond)
+}
+
+func TestMaxRate(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {}, serial.MaxRate(3))
+
+	for i := range 4 {
+		require.NoError(t, port.Send(ctx, []byte{byte(i)}))
+	}
+
+	var frames []written
+	for range 4 {
+		frames = append(frames, conn.next(t))
+	}
+
+	assert.Less(t, frames[2].at.Sub(frames[0].at), 500*time.Millisecond, "the first frames are not delayed")
+	assert.GreaterOrEqual(t, frames[3].at.Sub(frames[0].at), 950*time.Millisecond,
+		"the fourth frame waits for the first to leave the one second window")
+}
+
+func TestRequestRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {},
+		serial.RequestTimeout(20*time.Millisecond),
+		serial.RequestRetries(2),
+		serial.RetryBackoff(time.Millisecond),
+	)
+
+	_, err := port.Request(ctx, []byte{0x01}, func(packet.Packet) bool { return true })
+	require.ErrorIs(t, err, serial.ErrNoResponse)
+
+	assert.Len(t, conn.written, 3, "sent once and retried twice")
+}
+
+func TestRequestResponse(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {},
+		serial.RequestTimeout(50*time.Millisecond),
+		serial.RequestRetries(2),
+		serial.RetryBackoff(time.Millisecond),
+	)
+
+	// only the second attempt is answered, unrelated frames are no response
+	go func() {
+		<-conn.written
+		conn.rx <- []byte{0x02}
+
+		<-conn.written
+		conn.rx <- []byte{0x81}
+	}()
+
+	response, err := port.Request(ctx, []byte{0x01}, func(pkt packet.Packet) bool {
+		return pkt.ToString() == "81"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "81", response.ToString())
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+
+	newPort(ctx, cancel, transport, func(packet.Packet) {},
+		serial.ReconnectBackoff(10*time.Millisecond, 40*time.Millisecond))
+
+	var attempts []time.Time
+	for range 5 {
+		attempts = append(attempts, <-transport.attempts)
+	}
+
+	// doubled after every failed attempt, up to the longest backoff
+	for i, expected := range []time.Duration{10, 20, 40, 40} {
+		gap := attempts[i+1].Sub(attempts[i])
+		assert.GreaterOrEqual(t, gap, expected*time.Millisecond, "attempt %d", i+2)
+		assert.Less(t, gap, (expected+30)*time.Millisecond, "attempt %d", i+2)
+	}
+}
+
+func TestReconnectAfterClose(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	first := newFakeConn()
+	transport.conns <- first
+
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {})
+
+	require.NoError(t, port.Send(ctx, []byte{0x01}))
+	assert.Equal(t, []byte{0x01}, first.next(t).buf)
+
+	// the device is unplugged and comes back
+	first.Close()
+
+	assert.Eventually(t, func() bool {
+		return port.State() == serial.StateDisconnected
+	}, time.Second, time.Millisecond)
+
+	second := newFakeConn()
+	transport.conns <- second
+
+	require.NoError(t, port.Send(ctx, []byte{0x02}))
+	assert.Equal(t, []byte{0x02}, second.next(t).buf)
+	assert.Equal(t, serial.StateConnected, port.State())
+	assert.Equal(t, uint64(2), port.Stats().Connects)
+}
+
+func TestSendWaitsForConnection(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {})
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- port.Send(ctx, []byte{0x01})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, sent, "queued until the device is open")
+
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	require.NoError(t, <-sent)
+	assert.Equal(t, []byte{0x01}, conn.next(t).buf)
+}
+
+func TestFailOnDisconnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport := newFakeTransport()
+	port := newPort(ctx, cancel, transport, func(packet.Packet) {}, serial.FailOnDisconnect(true))
+
+	require.ErrorIs(t, port.Send(ctx, []byte{0x01}), serial.ErrDisconnected)
+
+	conn := newFakeConn()
+	transport.conns <- conn
+
+	assert.Eventually(t, func() bool {
+		return port.Send(ctx, []byte{0x02}) == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []byte{0x02}, conn.next(t).buf)
+}
@@ -0,0 +1,63 @@
+package serial
+
+import "github.com/MyChaOS87/reverseLCN/pkg/log"
+
+// State is the connection state of the serial device.
+type State int
+
+const (
+	StateDisconnected State = iota
+	StateConnecting
+	StateConnected
+)
+
+type StateCallback func(State)
+
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	default:
+		return "unknown"
+	}
+}
+
+func (p *port) State() State {
+	p.stateMutex.Lock()
+	defer p.stateMutex.Unlock()
+
+	return p.state
+}
+
+func (p *port) OnStateChange(callback StateCallback) {
+	p.stateMutex.Lock()
+	p.stateCallbacks = append(p.stateCallbacks, callback)
+	state := p.state
+	p.stateMutex.Unlock()
+
+	callback(state)
+}
+
+func (p *port) setState(state State) {
+	p.stateMutex.Lock()
+
+	if p.state == state {
+		p.stateMutex.Unlock()
+
+		return
+	}
+
+	p.state = state
+	callbacks := append([]StateCallback{}, p.stateCallbacks...)
+	p.stateMutex.Unlock()
+
+	log.Infof("serial(%s) %s", p.portName, state)
+
+	for _, callback := range callbacks {
+		callback(state)
+	}
+}
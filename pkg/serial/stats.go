@@ -0,0 +1,50 @@
+package serial
+
+import (
+	"sync/atomic"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker"
+)
+
+// Stats are counters of the port since it was created.
+type Stats struct {
+	Received    uint64 // packets received
+	Sent        uint64 // frames written
+	WriteErrors uint64
+	Connects    uint64 // successful opens of the device
+	QueueDepth  int
+
+	DroppedBytes     uint64 // received bytes not belonging to a frame
+	ChecksumFailures uint64
+	Resyncs          uint64 // times the frame boundaries were lost
+}
+
+// ChunkerStats returns Stats holding the counters of a chunker.
+func ChunkerStats(c chunker.Stats) Stats {
+	return Stats{
+		DroppedBytes:     c.DroppedBytes,
+		ChecksumFailures: c.ChecksumFailures,
+		Resyncs:          c.Resyncs,
+	}
+}
+
+type stats struct {
+	received    atomic.Uint64
+	sent        atomic.Uint64
+	writeErrors atomic.Uint64
+	connects    atomic.Uint64
+}
+
+func (p *port) Stats() Stats {
+	p.rxMutex.Lock()
+	stats := ChunkerStats(p.chunker.Stats())
+	p.rxMutex.Unlock()
+
+	stats.Received = p.stats.received.Load()
+	stats.Sent = p.stats.sent.Load()
+	stats.WriteErrors = p.stats.writeErrors.Load()
+	stats.Connects = p.stats.connects.Load()
+	stats.QueueDepth = p.QueueDepth()
+
+	return stats
+}
@@ -0,0 +1,105 @@
+package serial
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.bug.st/serial"
+)
+
+var ErrInvalidAddress = errors.New("invalid port address")
+
+// Conn is an open connection to the bus.
+// Read returns 0 bytes without error if nothing was received within the read timeout.
+type Conn interface {
+	io.ReadWriteCloser
+}
+
+// Transport opens connections to the bus.
+type Transport interface {
+	Open() (Conn, error)
+	String() string
+}
+
+// NewTransport selects the transport by the scheme of address:
+//
+//	/dev/ttyUSB0 or serial:///dev/ttyUSB0 - local serial device
+//	tcp://host:port                       - raw TCP, e.g. ser2net
+//	rfc2217://host:port                   - telnet with COM port control, mode is set remotely
+//	pipe://name                           - in-memory pipe created by NewPipe
+func NewTransport(address string, mode serial.Mode) (Transport, error) {
+	if !strings.Contains(address, "://") {
+		return &serialTransport{device: address, mode: mode}, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidAddress, err.Error())
+	}
+
+	switch u.Scheme {
+	case "serial":
+		if u.Path == "" {
+			return nil, errors.Wrapf(ErrInvalidAddress, "%s has no device", address)
+		}
+
+		return &serialTransport{device: u.Path, mode: mode}, nil
+	case "tcp":
+		if u.Host == "" {
+			return nil, errors.Wrapf(ErrInvalidAddress, "%s has no host", address)
+		}
+
+		return &tcpTransport{address: u.Host}, nil
+	case "rfc2217":
+		if u.Host == "" {
+			return nil, errors.Wrapf(ErrInvalidAddress, "%s has no host", address)
+		}
+
+		return &rfc2217Transport{address: u.Host, mode: mode}, nil
+	case "pipe":
+		return &pipeTransport{name: u.Host}, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidAddress, "unknown scheme %s", u.Scheme)
+	}
+}
+
+// failedTransport reports an invalid address on every attempt to open it.
+type failedTransport struct {
+	address string
+	err     error
+}
+
+func (t *failedTransport) Open() (Conn, error) {
+	return nil, t.err
+}
+
+func (t *failedTransport) String() string {
+	return t.address
+}
+
+type serialTransport struct {
+	device string
+	mode   serial.Mode
+}
+
+func (t *serialTransport) Open() (Conn, error) {
+	port, err := serial.Open(t.device, &t.mode)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open port %s", t.device)
+	}
+
+	err = port.SetReadTimeout(readTimeout)
+	if err != nil {
+		port.Close()
+
+		return nil, errors.Wrapf(err, "cannot set read timeout on serial(%s)", t.device)
+	}
+
+	return port, nil
+}
+
+func (t *serialTransport) String() string {
+	return t.device
+}
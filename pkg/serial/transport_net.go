@@ -0,0 +1,122 @@
+package serial
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const dialTimeout = 5 * time.Second
+
+var ErrPipeBusy = errors.New("pipe not accepting connections")
+
+// netConn turns read deadlines into empty reads, like the read timeout of a serial device.
+type netConn struct {
+	net.Conn
+}
+
+func (c *netConn) Read(buf []byte) (int, error) {
+	if err := c.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+		return 0, errors.Wrap(err, "cannot set read deadline")
+	}
+
+	n, err := c.Conn.Read(buf)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return n, nil
+	}
+
+	return n, err
+}
+
+type tcpTransport struct {
+	address string
+}
+
+func (t *tcpTransport) Open() (Conn, error) {
+	conn, err := net.DialTimeout("tcp", t.address, dialTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot connect to %s", t.address)
+	}
+
+	return &netConn{Conn: conn}, nil
+}
+
+func (t *tcpTransport) String() string {
+	return "tcp://" + t.address
+}
+
+//nolint:gochecknoglobals
+var (
+	pipesMutex sync.Mutex
+	pipes      = make(map[string]*Pipe)
+)
+
+// Pipe is the bus side of an in-memory transport, ports connect to it by the address pipe://<name>.
+type Pipe struct {
+	name  string
+	conns chan net.Conn
+}
+
+// NewPipe registers a pipe, a pipe with the same name is replaced.
+func NewPipe(name string) *Pipe {
+	p := &Pipe{
+		name:  name,
+		conns: make(chan net.Conn, 1),
+	}
+
+	pipesMutex.Lock()
+	pipes[name] = p
+	pipesMutex.Unlock()
+
+	return p
+}
+
+// Accept returns the bus side of the next connection opened by a port.
+func (p *Pipe) Accept() net.Conn {
+	return <-p.conns
+}
+
+// Close unregisters the pipe, ports cannot connect any more.
+func (p *Pipe) Close() {
+	pipesMutex.Lock()
+	defer pipesMutex.Unlock()
+
+	if pipes[p.name] == p {
+		delete(pipes, p.name)
+	}
+}
+
+type pipeTransport struct {
+	name string
+}
+
+func (t *pipeTransport) Open() (Conn, error) {
+	pipesMutex.Lock()
+	p, ok := pipes[t.name]
+	pipesMutex.Unlock()
+
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidAddress, "no pipe %s", t.name)
+	}
+
+	port, bus := net.Pipe()
+
+	select {
+	case p.conns <- bus:
+	default:
+		port.Close()
+		bus.Close()
+
+		return nil, errors.Wrap(ErrPipeBusy, t.name)
+	}
+
+	return &netConn{Conn: port}, nil
+}
+
+func (t *pipeTransport) String() string {
+	return "pipe://" + t.name
+}
@@ -0,0 +1,221 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	"github.com/pkg/errors"
+	"go.bug.st/serial"
+)
+
+// telnet (RFC 854) and COM port control (RFC 2217) codes.
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWill = 251
+	telnetWont = 252
+	telnetDo   = 253
+	telnetDont = 254
+	telnetIAC  = 255
+
+	optionBinary   = 0
+	optionSGA      = 3 // suppress go ahead
+	optionComPort  = 44
+	comSetBaudRate = 1
+	comSetDataSize = 2
+	comSetParity   = 3
+	comSetStopSize = 4
+	comSetControl  = 5
+
+	comControlNoFlowControl = 1
+)
+
+// rfc2217Parity maps serial.Parity to the values of SET-PARITY.
+//
+//nolint:gochecknoglobals
+var rfc2217Parity = map[serial.Parity]byte{
+	serial.NoParity:    1,
+	serial.OddParity:   2,
+	serial.EvenParity:  3,
+	serial.MarkParity:  4,
+	serial.SpaceParity: 5,
+}
+
+// rfc2217StopBits maps serial.StopBits to the values of SET-STOPSIZE.
+//
+//nolint:gochecknoglobals
+var rfc2217StopBits = map[serial.StopBits]byte{
+	serial.OneStopBit:           1,
+	serial.TwoStopBits:          2,
+	serial.OnePointFiveStopBits: 3,
+}
+
+type rfc2217Transport struct {
+	address string
+	mode    serial.Mode
+}
+
+func (t *rfc2217Transport) Open() (Conn, error) {
+	conn, err := net.DialTimeout("tcp", t.address, dialTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot connect to %s", t.address)
+	}
+
+	c := &rfc2217Conn{netConn: netConn{Conn: conn}}
+
+	if _, err := conn.Write(t.negotiation()); err != nil {
+		conn.Close()
+
+		return nil, errors.Wrapf(err, "cannot configure %s", t.address)
+	}
+
+	return c, nil
+}
+
+// negotiation enables binary transmission and COM port control and sets the mode of the remote port.
+func (t *rfc2217Transport) negotiation() []byte {
+	buf := []byte{
+		telnetIAC, telnetWill, optionBinary,
+		telnetIAC, telnetDo, optionBinary,
+		telnetIAC, telnetWill, optionSGA,
+		telnetIAC, telnetDo, optionSGA,
+		telnetIAC, telnetWill, optionComPort,
+	}
+
+	subnegotiation := func(command byte, value ...byte) {
+		buf = append(buf, telnetIAC, telnetSB, optionComPort, command)
+		buf = append(buf, escapeIAC(value)...)
+		buf = append(buf, telnetIAC, telnetSE)
+	}
+
+	subnegotiation(comSetBaudRate, binary.BigEndian.AppendUint32(nil, uint32(t.mode.BaudRate))...)
+	subnegotiation(comSetDataSize, byte(t.mode.DataBits))
+	subnegotiation(comSetParity, rfc2217Parity[t.mode.Parity])
+	subnegotiation(comSetStopSize, rfc2217StopBits[t.mode.StopBits])
+	subnegotiation(comSetControl, comControlNoFlowControl)
+
+	return buf
+}
+
+func (t *rfc2217Transport) String() string {
+	return "rfc2217://" + t.address
+}
+
+func escapeIAC(buf []byte) []byte {
+	return bytes.ReplaceAll(buf, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC})
+}
+
+// rfc2217Conn strips telnet commands from received data and escapes sent data.
+type rfc2217Conn struct {
+	netConn
+
+	pending []byte // incomplete telnet command of the last read
+}
+
+func (c *rfc2217Conn) Write(buf []byte) (int, error) {
+	if _, err := c.netConn.Write(escapeIAC(buf)); err != nil {
+		return 0, err
+	}
+
+	return len(buf), nil
+}
+
+func (c *rfc2217Conn) Read(buf []byte) (int, error) {
+	raw := make([]byte, len(buf))
+
+	n, err := c.netConn.Read(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	data := append(c.pending, raw[:n]...)
+	c.pending = nil
+
+	out := buf[:0]
+
+	for i := 0; i < len(data); i++ {
+		if data[i] != telnetIAC {
+			out = append(out, data[i])
+
+			continue
+		}
+
+		length := commandLength(data[i:])
+		if length == 0 {
+			c.pending = append([]byte{}, data[i:]...)
+
+			break
+		}
+
+		if data[i+1] == telnetIAC {
+			out = append(out, telnetIAC)
+		} else if err := c.answer(data[i : i+length]); err != nil {
+			return len(out), err
+		}
+
+		i += length - 1
+	}
+
+	return len(out), nil
+}
+
+// commandLength returns the length of the telnet command at the start of buf, 0 if it is incomplete.
+func commandLength(buf []byte) int {
+	if len(buf) < 2 {
+		return 0
+	}
+
+	switch buf[1] {
+	case telnetWill, telnetWont, telnetDo, telnetDont:
+		if len(buf) < 3 {
+			return 0
+		}
+
+		return 3
+	case telnetSB:
+		for i := 2; i+1 < len(buf); i++ {
+			if buf[i] == telnetIAC && buf[i+1] == telnetSE {
+				return i + 2
+			}
+
+			if buf[i] == telnetIAC && buf[i+1] == telnetIAC {
+				i++
+			}
+		}
+
+		return 0
+	default:
+		return 2
+	}
+}
+
+// answer refuses options we did not ask for, everything else is ignored.
+func (c *rfc2217Conn) answer(command []byte) error {
+	if len(command) != 3 {
+		return nil
+	}
+
+	var refusal byte
+
+	switch command[1] {
+	case telnetDo:
+		if command[2] == optionBinary || command[2] == optionSGA || command[2] == optionComPort {
+			return nil
+		}
+
+		refusal = telnetWont
+	case telnetWill:
+		if command[2] == optionBinary || command[2] == optionSGA {
+			return nil
+		}
+
+		refusal = telnetDont
+	default:
+		return nil
+	}
+
+	_, err := c.netConn.Write([]byte{telnetIAC, refusal, command[2]})
+
+	return errors.Wrap(err, "cannot answer telnet negotiation")
+}
@@ -0,0 +1,156 @@
+package serial_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bugst "go.bug.st/serial"
+
+	"github.com/MyChaOS87/reverseLCN/pkg/serial"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/packet"
+	"github.com/MyChaOS87/reverseLCN/pkg/serial/chunker/plain"
+)
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+		error    error
+	}{
+		{address: "/dev/ttyUSB0", expected: "/dev/ttyUSB0"},
+		{address: "serial:///dev/ttyUSB0", expected: "/dev/ttyUSB0"},
+		{address: "tcp://pi:2000", expected: "tcp://pi:2000"},
+		{address: "rfc2217://pi:2001", expected: "rfc2217://pi:2001"},
+		{address: "pipe://test", expected: "pipe://test"},
+		{address: "serial://", error: serial.ErrInvalidAddress},
+		{address: "tcp://", error: serial.ErrInvalidAddress},
+		{address: "udp://pi:2000", error: serial.ErrInvalidAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			transport, err := serial.NewTransport(tt.address, bugst.Mode{BaudRate: 9600})
+			if tt.error != nil {
+				assert.ErrorIs(t, err, tt.error)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, transport.String())
+		})
+	}
+}
+
+func readFull(t *testing.T, conn net.Conn, length int) []byte {
+	t.Helper()
+
+	buf := make([]byte, length)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err := io.ReadFull(conn, buf)
+	require.NoError(t, err)
+
+	return buf
+}
+
+func TestPipe(t *testing.T) {
+	pipe := serial.NewPipe(t.Name())
+	defer pipe.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 10)
+
+	port := serial.NewPort(
+		serial.PortName("pipe://"+t.Name()),
+		serial.BusIdle(0),
+		serial.FrameSpacing(0),
+		serial.ReconnectBackoff(time.Millisecond, time.Millisecond),
+	)
+	port.Run(ctx, cancel, func(pkt packet.Packet) {
+		received <- pkt.ToString()
+	})
+
+	bus := pipe.Accept()
+
+	// pipes are synchronous, writes block until the other side reads
+	sent := make(chan error)
+
+	go func() {
+		sent <- port.Send(ctx, []byte{0x01, 0x02})
+	}()
+
+	assert.Equal(t, []byte{0x01, 0x02}, readFull(t, bus, 2))
+	require.NoError(t, <-sent)
+
+	_, err := bus.Write([]byte{0xa8})
+	require.NoError(t, err)
+	assert.Equal(t, "a8", <-received)
+
+	// the port reconnects once the bus side goes away
+	bus.Close()
+
+	bus = pipe.Accept()
+
+	go func() {
+		if _, err := io.ReadFull(bus, make([]byte, 1)); err == nil {
+			bus.Write([]byte{0x42}) //nolint:errcheck
+		}
+	}()
+
+	response, err := port.Request(ctx, []byte{0x41}, func(pkt packet.Packet) bool {
+		return pkt.ToString() == "42"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &plain.Plain{0x42}, response)
+	assert.Equal(t, uint64(2), port.Stats().Connects)
+}
+
+func TestRFC2217(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 10)
+
+	port := serial.NewPort(
+		serial.PortName("rfc2217://"+listener.Addr().String()),
+		serial.BaudRate(9600),
+		serial.BusIdle(0),
+		serial.FrameSpacing(0),
+	)
+	port.Run(ctx, cancel, func(pkt packet.Packet) {
+		received <- pkt.ToString()
+	})
+
+	server, err := listener.Accept()
+	require.NoError(t, err)
+	defer server.Close()
+
+	negotiation := readFull(t, server, 15+4*7+3+7)
+	assert.True(t, bytes.HasPrefix(negotiation, []byte{255, 251, 0, 255, 253, 0}), "binary mode")
+	assert.True(t, bytes.Contains(negotiation, []byte{255, 250, 44, 1, 0, 0, 0x25, 0x80, 255, 240}), "9600 baud")
+
+	// data bytes 0xff are escaped
+	require.NoError(t, port.Send(ctx, []byte{0xff, 0x01}))
+	assert.Equal(t, []byte{0xff, 0xff, 0x01}, readFull(t, server, 3))
+
+	// telnet commands are stripped, unknown options refused
+	_, err = server.Write([]byte{0xa8, 255, 253, 1, 255, 255, 255, 250, 44, 101, 0, 0, 0x25, 0x80, 255, 240, 0x06})
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte{255, 252, 1}, readFull(t, server, 3))
+	assert.Equal(t, "a8", <-received)
+	assert.Equal(t, "ff", <-received)
+	assert.Equal(t, "06", <-received)
+}
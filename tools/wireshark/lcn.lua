@@ -0,0 +1,66 @@
+-- Wireshark dissector for pcapng files written by lcnDump and lcn2mqtt (capture.pcapng).
+-- Copy to your personal plugins folder, packets use link type USER0 (DLT 147).
+
+local lcn = Proto("lcn", "LCN bus")
+
+local kinds = { [0] = "frame", [1] = "unframed", [2] = "checksum failure" }
+local directions = { [0] = "rx", [1] = "tx" }
+
+local f = lcn.fields
+f.kind = ProtoField.uint8("lcn.kind", "Kind", base.DEC, kinds)
+f.dir = ProtoField.uint8("lcn.dir", "Direction", base.DEC, directions)
+f.src = ProtoField.uint8("lcn.src", "Source", base.DEC)
+f.info = ProtoField.uint8("lcn.info", "Info", base.HEX)
+f.checksum = ProtoField.uint8("lcn.checksum", "Checksum", base.HEX)
+f.seg = ProtoField.uint8("lcn.seg", "Segment", base.DEC)
+f.dst = ProtoField.uint8("lcn.dst", "Destination", base.DEC)
+f.cmd = ProtoField.uint8("lcn.cmd", "Command", base.HEX)
+f.payload = ProtoField.bytes("lcn.payload", "Payload")
+f.data = ProtoField.bytes("lcn.data", "Data")
+
+-- the source ID is transmitted bit mirrored
+local function mirror(b)
+	local r = 0
+	for _ = 1, 8 do
+		r = r * 2 + b % 2
+		b = math.floor(b / 2)
+	end
+	return r
+end
+
+function lcn.dissector(buffer, pinfo, tree)
+	if buffer:len() < 2 then
+		return
+	end
+
+	pinfo.cols.protocol = "LCN"
+
+	local kind = buffer(0, 1):uint()
+	local subtree = tree:add(lcn, buffer(), "LCN bus")
+	subtree:add(f.kind, buffer(0, 1))
+	subtree:add(f.dir, buffer(1, 1))
+
+	local frame = buffer(2)
+	if kind == 1 or frame:len() < 6 then
+		subtree:add(f.data, frame)
+		pinfo.cols.info = (kinds[kind] or "unknown") .. " " .. tostring(frame:bytes())
+		return
+	end
+
+	local src = mirror(frame(0, 1):uint())
+	subtree:add(f.src, frame(0, 1), src)
+	subtree:add(f.info, frame(1, 1))
+	subtree:add(f.checksum, frame(2, 1))
+	subtree:add(f.seg, frame(3, 1))
+	subtree:add(f.dst, frame(4, 1))
+	subtree:add(f.cmd, frame(5, 1))
+	if frame:len() > 6 then
+		subtree:add(f.payload, frame(6))
+	end
+
+	pinfo.cols.src = tostring(src)
+	pinfo.cols.dst = string.format("%d:%d", frame(3, 1):uint(), frame(4, 1):uint())
+	pinfo.cols.info = string.format("%s cmd 0x%02x", kinds[kind] or "unknown", frame(5, 1):uint())
+end
+
+DissectorTable.get("wtap_encap"):add(wtap.USER0, lcn)